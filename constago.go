@@ -3,6 +3,7 @@ package main
 import (
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 
 	constago "github.com/cohesivestack/constago/lib"
@@ -30,6 +31,14 @@ func main() {
 func loadConfigFromViper(v *viper.Viper) (*constago.Config, error) {
 	raw := &constago.Config{}
 
+	// Pattern lists coming from ENV are plain strings; split them here so
+	// commas inside brace expansions (e.g. "**/{a,b}/*.go") survive
+	for _, key := range []string{"input.include", "input.exclude"} {
+		if s, ok := v.Get(key).(string); ok {
+			v.Set(key, splitPatternList(s))
+		}
+	}
+
 	// Use yaml tags to decode into the structs.
 	if err := v.Unmarshal(raw, func(dc *mapstructure.DecoderConfig) {
 		dc.TagName = "yaml"
@@ -45,6 +54,37 @@ func loadConfigFromViper(v *viper.Viper) (*constago.Config, error) {
 	return cfg, nil
 }
 
+// splitPatternList splits a list of glob patterns separated by newlines or
+// by commas that are not inside a brace expansion.
+func splitPatternList(s string) []string {
+	var patterns []string
+	var current strings.Builder
+	depth := 0
+
+	flush := func() {
+		if p := strings.TrimSpace(current.String()); p != "" {
+			patterns = append(patterns, p)
+		}
+		current.Reset()
+	}
+
+	for _, r := range s {
+		switch {
+		case r == '{':
+			depth++
+		case r == '}' && depth > 0:
+			depth--
+		case r == '\n' || (r == ',' && depth == 0):
+			flush()
+			continue
+		}
+		current.WriteRune(r)
+	}
+	flush()
+
+	return patterns
+}
+
 // initViper sets up Viper with config file (optional), env prefix + replacer, and binds all flags.
 func initViper(cmd *cobra.Command) (*viper.Viper, error) {
 	v := viper.New()
@@ -74,6 +114,9 @@ func initViper(cmd *cobra.Command) (*viper.Viper, error) {
 	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	v.AutomaticEnv()
 
+	// --force is a shorthand for output.force
+	v.RegisterAlias("force", "output.force")
+
 	return v, nil
 }
 
@@ -136,38 +179,119 @@ func newRootCmd(run func(*constago.Config) error) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "constago",
 		Short: "Generate constants and getters from project structs/tags",
+		// Positional args are ignored, as they were before subcommands existed
+		Args: cobra.ArbitraryArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			v, err := initViper(cmd)
+			cfg, err := loadConfigFromCmd(cmd)
 			if err != nil {
 				return err
 			}
+			if run == nil {
+				return nil
+			}
+			return run(cfg)
+		},
+	}
+
+	addConfigFlags(cmd)
+	cmd.Flags().Bool("force", false, "Rewrite the output files even when their content is unchanged")
 
-			// Important: apply *only* flags the user passed
-			if err := applyChangedFlagsToViper(cmd, v); err != nil {
+	// Add help text for simplified configuration
+	cmd.Long = `Constago generates constants and getter functions from Go structs.
+
+The tool supports configuration via:
+- YAML config file (recommended for all setups)
+- Command line flags (for basic input/output overrides)
+- Environment variables (CONSTAGO_* prefix)
+
+Elements and getters configuration must be done via YAML config file.
+CLI flags only support basic input and output parameters.
+
+Examples:
+  constago --config constago.yaml
+  constago --input.dir ./src --output.file_name constants.go
+  constago --input.include "**/*.go" --input.exclude "**/*_test.go"
+  constago lint --config constago.yaml`
+
+	cmd.AddCommand(newLintCmd())
+
+	return cmd
+}
+
+// newLintCmd creates the lint subcommand, which reports the elements and
+// getters that produce nothing, failing when any is found.
+func newLintCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lint",
+		Short: "Report elements and getters that produce no output",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfigFromCmd(cmd)
+			if err != nil {
 				return err
 			}
 
-			cfg, err := loadConfigFromViper(v)
+			report, err := constago.Lint(cfg)
 			if err != nil {
 				return err
 			}
-			if run == nil {
-				return nil
+
+			out := cmd.OutOrStdout()
+			for _, name := range report.UnusedElements {
+				fmt.Fprintf(out, "element %q produced no values\n", name)
 			}
-			return run(cfg)
+			unusedElements := len(report.UnusedElements)
+			for _, name := range report.UnreturnedElements {
+				fmt.Fprintf(out, "element %q has the none output mode but no getter returns it\n", name)
+				if !slices.Contains(report.UnusedElements, name) {
+					unusedElements++
+				}
+			}
+			for _, name := range report.UnusedGetters {
+				fmt.Fprintf(out, "getter %q produced no getters\n", name)
+			}
+			if !report.IsEmpty() {
+				cmd.SilenceUsage = true
+				return fmt.Errorf("found %d unused elements and %d unused getters", unusedElements, len(report.UnusedGetters))
+			}
+			return nil
 		},
 	}
 
+	addConfigFlags(cmd)
+
+	return cmd
+}
+
+// loadConfigFromCmd merges the config file, ENV and the flags passed to cmd
+// into a Config.
+func loadConfigFromCmd(cmd *cobra.Command) (*constago.Config, error) {
+	v, err := initViper(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	// Important: apply *only* flags the user passed
+	if err := applyChangedFlagsToViper(cmd, v); err != nil {
+		return nil, err
+	}
+
+	return loadConfigFromViper(v)
+}
+
+// addConfigFlags registers the config file, input and output flags.
+func addConfigFlags(cmd *cobra.Command) {
 	// Global
 	cmd.Flags().String("config", "", "Path to YAML config file")
 
 	// ---------- INPUT ----------
 	cmd.Flags().String("input.dir", "", "Directory to scan (e.g., ./)")
-	cmd.Flags().StringSlice("input.include", nil, "Glob patterns to include (comma-separated for ENV)")
-	cmd.Flags().StringSlice("input.exclude", nil, "Glob patterns to exclude (comma-separated for ENV)")
+	cmd.Flags().StringSlice("input.include", nil, "Glob patterns to include (comma or newline separated for ENV, braces are kept)")
+	cmd.Flags().StringSlice("input.exclude", nil, "Glob patterns to exclude (comma or newline separated for ENV, braces are kept)")
+	cmd.Flags().Bool("input.resolve_types", true, "Resolve package names of :value getter types with the go toolchain")
 
 	cmd.Flags().Bool("input.struct.explicit", false, "Only include structs explicitly marked")
 	cmd.Flags().Bool("input.struct.include_unexported", false, "Include unexported structs when scanning")
+	cmd.Flags().Bool("input.struct.include_anonymous", false, "Include aliases to anonymous structs when scanning")
 
 	cmd.Flags().String("input.struct.include_only", "", "Regular expression to include structs (whitelist)")
 	cmd.Flags().String("input.struct.include_except", "", "Regular expression to exclude structs (blacklist)")
@@ -180,22 +304,5 @@ func newRootCmd(run func(*constago.Config) error) *cobra.Command {
 
 	// ---------- OUTPUT ----------
 	cmd.Flags().String("output.file_name", "", "Output file name (e.g., constants_gen.go)")
-
-	// Add help text for simplified configuration
-	cmd.Long = `Constago generates constants and getter functions from Go structs.
-
-The tool supports configuration via:
-- YAML config file (recommended for all setups)
-- Command line flags (for basic input/output overrides)
-- Environment variables (CONSTAGO_* prefix)
-
-Elements and getters configuration must be done via YAML config file.
-CLI flags only support basic input and output parameters.
-
-Examples:
-  constago --config constago.yaml
-  constago --input.dir ./src --output.file_name constants.go
-  constago --input.include "**/*.go" --input.exclude "**/*_test.go"`
-
-	return cmd
+	cmd.Flags().String("output.package_name", "", "Package name for the generated files (defaults to the source package)")
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
 	"testing"
@@ -136,3 +137,106 @@ const (
 )`
 	assert.Contains(t, string(data), expectedChunk)
 }
+
+func TestNewRootCmd_EnvPatternListsKeepBraces(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      string
+		expected []string
+	}{
+		{
+			name:     "comma separated",
+			env:      "**/{model,dto}/*.go,cmd/*.go",
+			expected: []string{"**/{model,dto}/*.go", "cmd/*.go"},
+		},
+		{
+			name:     "newline separated",
+			env:      "**/{model,dto}/*.go\ncmd/*.go\n",
+			expected: []string{"**/{model,dto}/*.go", "cmd/*.go"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("CONSTAGO_INPUT_INCLUDE", tt.env)
+
+			var captured *constago.Config
+			cmd := newRootCmd(func(cfg *constago.Config) error {
+				captured = cfg
+				return nil
+			})
+			cmd.SetArgs([]string{"--input.dir", t.TempDir()})
+
+			require.NoError(t, cmd.Execute())
+			require.NotNil(t, captured)
+			assert.Equal(t, tt.expected, captured.Input.Include)
+		})
+	}
+}
+
+func TestNewRootCmd_ForceFlag(t *testing.T) {
+	var captured *constago.Config
+	cmd := newRootCmd(func(cfg *constago.Config) error {
+		captured = cfg
+		return nil
+	})
+
+	cmd.SetArgs([]string{"--input.dir", t.TempDir(), "--force"})
+	require.NoError(t, cmd.Execute())
+	require.NotNil(t, captured)
+
+	if assert.NotNil(t, captured.Output.Force) {
+		assert.True(t, *captured.Output.Force)
+	}
+}
+
+func TestCLI_LintReportsUnusedElements(t *testing.T) {
+	tmp := t.TempDir()
+
+	goFile := filepath.Join(tmp, "user.go")
+	src := `package main
+
+type User struct {
+    Name string ` + "`json:\"name\"`" + `
+}`
+	require.NoError(t, os.WriteFile(goFile, []byte(src), 0644))
+
+	cfgFile := filepath.Join(tmp, "constago.yaml")
+	yaml := `input:
+  dir: "` + tmp + `"
+elements:
+  - name: "json"
+    input:
+      mode: "tag"
+      tag_priority:
+        - "json"
+  - name: "xml"
+    input:
+      mode: "tag"
+      tag_priority:
+        - "xml"
+  - name: "db"
+    input:
+      mode: "tag"
+      tag_priority:
+        - "db"
+    output:
+      mode: "none"
+`
+	require.NoError(t, os.WriteFile(cfgFile, []byte(yaml), 0644))
+
+	cmd := newRootCmd(nil)
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs([]string{"lint", "--config", cfgFile})
+
+	// db is reported twice but counted once
+	err := cmd.Execute()
+	assert.ErrorContains(t, err, "found 2 unused elements and 0 unused getters")
+	assert.Contains(t, out.String(), "element \"xml\" produced no values\n")
+	assert.Contains(t, out.String(), "element \"db\" produced no values\n")
+	assert.Contains(t, out.String(), "element \"db\" has the none output mode but no getter returns it\n")
+	assert.NotContains(t, out.String(), "\"json\"")
+	assert.NoFileExists(t, filepath.Join(tmp, "constago.gen.go"))
+}
@@ -1,23 +1,69 @@
 package constago
 
 import (
+	"bytes"
 	_ "embed"
+	"errors"
 	"fmt"
+	"go/format"
+	"go/parser"
+	"go/token"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"text/template"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/imports"
 )
 
 const templateName = "code_template.tpl"
 
+// generatedHeader is the first line written by the code template, used to
+// recognize generated files when scanning
+const generatedHeader = "// Code generated by constago generator; DO NOT EDIT."
+
+// Markers delimiting the generated region of a hand-maintained file when
+// output.merge_markers is enabled
+const (
+	mergeBeginMarker = "// constago:begin"
+	mergeEndMarker   = "// constago:end"
+)
+
+const docsTemplateName = "docs_template.tpl"
+
 //go:embed code_template.tpl
 var codeTemplate string
 
+// docsTemplate renders a Markdown table per struct for output.format markdown
+//
+//go:embed docs_template.tpl
+var docsTemplate string
+
 type generator struct {
 	model *Model
 }
 
+// OutputWriter receives the generated content of every package, so embedders
+// can send the code elsewhere than the output files, e.g. to an archive. With
+// output.split_exported it receives the exported and the unexported parts of
+// a package apart, with the same Path and their own FileName.
+type OutputWriter interface {
+	WritePackage(pkg *PackageModel, content []byte) error
+}
+
+// Generate writes the generated code of every package to its output file
 func Generate(config *Config) error {
+	return GenerateTo(config, nil)
+}
+
+// GenerateTo hands the generated code of every package to the writer, or
+// writes it to the output files when the writer is nil
+func GenerateTo(config *Config, writer OutputWriter) error {
 	cfg, err := NewConfig(config)
 	if err != nil {
 		return fmt.Errorf("failed to create config: %w", err)
@@ -33,44 +79,513 @@ func Generate(config *Config) error {
 	g := &generator{model: model}
 
 	// Parse the template
-	tmpl, err := template.New(templateName).Parse(codeTemplate)
+	tmpl, entry, err := parseTemplates(cfg)
 	if err != nil {
-		return fmt.Errorf("failed to parse template: %w", err)
+		return err
+	}
+
+	var pathTmpl *template.Template
+	if !isStringBlank(cfg.Output.PathTemplate) {
+		pathTmpl, err = template.New("path_template").Parse(cfg.Output.PathTemplate)
+		if err != nil {
+			return fmt.Errorf("failed to parse output path template: %w", err)
+		}
+	}
+	// Output files are formatted once merged, other writers get the code
+	// formatted as it would be written
+	formatted := writer != nil
+	if writer == nil {
+		writer = &fileWriter{output: &cfg.Output, pathTmpl: pathTmpl}
+	}
+
+	var docTmpl *template.Template
+	if !isStringBlank(cfg.Output.PackageDoc) {
+		docTmpl, err = template.New("package_doc").Parse(cfg.Output.PackageDoc)
+		if err != nil {
+			return fmt.Errorf("failed to parse package doc template: %w", err)
+		}
 	}
 
 	// Generate code for each package
-	for _, pkg := range g.model.Packages {
-		if len(pkg.Structs) == 0 {
-			continue // Skip packages with no structs to generate
+	generatePackage := func(pkg *PackageModel) error {
+		if len(pkg.Structs) == 0 && len(pkg.MapKeys) == 0 {
+			return nil // Skip packages with nothing to generate
+		}
+		if cfg.Output.Format == OutputFormatGo && !pkg.hasCode() {
+			return nil // Skip packages only documented by doc elements
+		}
+
+		packageName := pkg.Name
+		if !isStringBlank(cfg.Output.PackageName) {
+			packageName = cfg.Output.PackageName
+			// Next to the sources only the external test package can differ
+			if cfg.Output.Format == OutputFormatGo && pathTmpl == nil && packageName != pkg.Name+"_test" {
+				return fmt.Errorf("output.package_name %s can't be written to %s, which holds package %s, only %s_test can", packageName, pkg.Path, pkg.Name, pkg.Name)
+			}
+		}
+
+		packageDoc := ""
+		if docTmpl != nil {
+			var err error
+			packageDoc, err = renderPackageDoc(docTmpl, pkg, packageName)
+			if err != nil {
+				return err
+			}
+		}
+
+		writePackage := func(pkg *PackageModel, packageDoc string, fileName string) error {
+			// The writer gets a copy naming the file, leaving the model as built
+			named := *pkg
+			named.FileName = fileName
+			pkg = &named
+
+			templateData := struct {
+				Config       *Config
+				Package      *PackageModel
+				PackageName  string
+				PackageDoc   string
+				MergeMarkers bool
+			}{
+				Config:       cfg,
+				Package:      pkg,
+				PackageName:  packageName,
+				PackageDoc:   packageDoc,
+				MergeMarkers: cfg.Output.isMergeMarkers(),
+			}
+
+			var output bytes.Buffer
+			err := tmpl.ExecuteTemplate(&output, entry, templateData)
+			if err != nil {
+				return fmt.Errorf("failed to execute template for %s: %w", pkg.Path, err)
+			}
+
+			content := output.Bytes()
+			if formatted {
+				content, err = formatOutput(filepath.Join(pkg.Path, fileName), content, &cfg.Output)
+				if err != nil {
+					return err
+				}
+			}
+			return writer.WritePackage(pkg, content)
 		}
 
-		// Create output directory if it doesn't exist
-		outputDir := pkg.Path
-		if err := os.MkdirAll(outputDir, 0755); err != nil {
-			return fmt.Errorf("failed to create output directory %s: %w", outputDir, err)
+		if !cfg.Output.isSplitExported() {
+			return writePackage(pkg, packageDoc, cfg.Output.FileName)
 		}
 
-		fileName := filepath.Join(outputDir, cfg.Output.FileName)
+		// The package doc goes with the first file written
+		exported, internal := pkg.splitExported()
+		exported.FileName = cfg.Output.FileName
+		internal.FileName = cfg.Output.internalFileName()
+		// Output files named by a path template ignoring the file name would
+		// hold the internal part only
+		if pathTmpl != nil && !formatted && exported.hasCode() && internal.hasCode() {
+			exportedPath, err := outputPath(pathTmpl, exported, exported.FileName)
+			if err != nil {
+				return err
+			}
+			internalPath, err := outputPath(pathTmpl, internal, internal.FileName)
+			if err != nil {
+				return err
+			}
+			if exportedPath == internalPath {
+				return fmt.Errorf("output.path_template writes the exported and the unexported declarations of %s to the same file %s, use {{.FileName}} in it with output.split_exported", pkg.Path, exportedPath)
+			}
+		}
+		if exported.hasCode() {
+			if err := writePackage(exported, packageDoc, cfg.Output.FileName); err != nil {
+				return err
+			}
+			packageDoc = ""
+		}
+		if internal.hasCode() {
+			return writePackage(internal, packageDoc, cfg.Output.internalFileName())
+		}
+		return nil
+	}
 
-		templateData := struct {
-			Config  *Config
-			Package *PackageModel
-		}{
-			Config:  cfg,
-			Package: pkg,
+	paths := make([]string, 0, len(g.model.Packages))
+	for pkgPath := range g.model.Packages {
+		paths = append(paths, pkgPath)
+	}
+	sort.Strings(paths)
+
+	errs := &packageErrors{}
+	if cfg.Output.isParallel() {
+		var wg sync.WaitGroup
+		for _, pkgPath := range paths {
+			wg.Add(1)
+			go func(pkg *PackageModel) {
+				defer wg.Done()
+				errs.add(pkg.Path, generatePackage(pkg))
+			}(g.model.Packages[pkgPath])
 		}
+		wg.Wait()
+	} else {
+		for _, pkgPath := range paths {
+			pkg := g.model.Packages[pkgPath]
+			errs.add(pkg.Path, generatePackage(pkg))
+		}
+	}
 
-		output, err := os.Create(fileName)
+	return errs.err()
+}
+
+// packageErrors collects the errors of the generated packages, which may come
+// from concurrent goroutines with output.parallel, so they are reported in
+// the same order on every run
+type packageErrors struct {
+	mu     sync.Mutex
+	byPath map[string]error
+}
+
+func (e *packageErrors) add(pkgPath string, err error) {
+	if err == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.byPath == nil {
+		e.byPath = map[string]error{}
+	}
+	e.byPath[pkgPath] = err
+}
+
+// err returns the collected errors sorted by package path, joined when there
+// are several, or nil
+func (e *packageErrors) err() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	paths := make([]string, 0, len(e.byPath))
+	for p := range e.byPath {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	errs := make([]error, len(paths))
+	for i, p := range paths {
+		errs[i] = e.byPath[p]
+	}
+	if len(errs) == 1 {
+		return errs[0]
+	}
+	return errors.Join(errs...)
+}
+
+// fileWriter is the OutputWriter of Generate, writing each package to the file
+// named by its FileName or output.path_template
+type fileWriter struct {
+	output   *ConfigOutput
+	pathTmpl *template.Template
+}
+
+func (w *fileWriter) WritePackage(pkg *PackageModel, content []byte) error {
+	fileName := filepath.Join(pkg.Path, pkg.FileName)
+	if w.pathTmpl != nil {
+		var err error
+		fileName, err = outputPath(w.pathTmpl, pkg, pkg.FileName)
 		if err != nil {
-			return fmt.Errorf("failed to create output file %s: %w", fileName, err)
+			return err
+		}
+	}
+
+	if w.output.Format == OutputFormatGo {
+		if err := checkInternalImports(fileName, pkg); err != nil {
+			return err
 		}
-		defer output.Close()
+	}
+
+	// Create output directory if it doesn't exist
+	outputDir := filepath.Dir(fileName)
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory %s: %w", outputDir, err)
+	}
+
+	if w.output.isMergeMarkers() {
+		existing, err := os.ReadFile(fileName)
+		if err == nil {
+			return mergeGeneratedRegion(fileName, existing, content, w.output)
+		}
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read output file %s: %w", fileName, err)
+		}
+		// A missing file is created with the markers in place
+	}
+
+	return writeOutput(fileName, content, w.output)
+}
+
+// checkInternalImports returns an error when the output file would import an
+// internal package from outside the tree rooted at the parent of its internal
+// directory, which Go doesn't allow. The import path of the file is taken
+// from the module enclosing it, so files outside a module aren't checked.
+func checkInternalImports(fileName string, pkg *PackageModel) error {
+	paths := make([]string, 0, len(pkg.Imports))
+	for p := range pkg.Imports {
+		if _, ok := internalRoot(p); ok {
+			paths = append(paths, p)
+		}
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+	sort.Strings(paths)
+
+	moduleDir, modulePath := locateGoModule(fileName)
+	if modulePath == "" {
+		return nil
+	}
+	rel, err := filepath.Rel(moduleDir, filepath.Dir(fileName))
+	if err != nil {
+		return nil
+	}
+	importPath := modulePath
+	if rel != "." {
+		importPath = path.Join(modulePath, filepath.ToSlash(rel))
+	}
 
-		err = tmpl.Execute(output, templateData)
+	for _, p := range paths {
+		root, _ := internalRoot(p)
+		if importPath != root && !strings.HasPrefix(importPath, root+"/") {
+			return fmt.Errorf("output file %s can't import the internal package %s, only allowed within %s", fileName, p, root)
+		}
+	}
+	return nil
+}
+
+// internalRoot returns the import path of the tree allowed to import an
+// internal package, which is the parent of its last internal element
+func internalRoot(importPath string) (string, bool) {
+	parts := strings.Split(importPath, "/")
+	for i := len(parts) - 1; i > 0; i-- {
+		if parts[i] == "internal" {
+			return strings.Join(parts[:i], "/"), true
+		}
+	}
+	return "", false
+}
+
+// templateFuncs are the functions available to the built-in template and to
+// the templates of output.templates
+var templateFuncs = template.FuncMap{
+	"lower":  strings.ToLower,
+	"upper":  strings.ToUpper,
+	"camel":  toCamelCase,
+	"pascal": toPascalCase,
+	// markdownCell escapes the pipes of a value written in a Markdown table
+	"markdownCell": func(s string) string {
+		return strings.ReplaceAll(s, "|", "\\|")
+	},
+}
+
+// parseTemplates parses the built-in template together with the ones of
+// output.templates, so they can use each other, and returns the name of the
+// template generating the files, which is the docs template for the markdown
+// format unless output.templates is set
+func parseTemplates(cfg *Config) (*template.Template, string, error) {
+	tmpl, err := template.New(templateName).Funcs(templateFuncs).Parse(codeTemplate)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse template: %w", err)
+	}
+	if len(cfg.Output.Templates) == 0 {
+		if cfg.Output.Format == OutputFormatMarkdown {
+			tmpl, err = tmpl.New(docsTemplateName).Parse(docsTemplate)
+			if err != nil {
+				return nil, "", fmt.Errorf("failed to parse docs template: %w", err)
+			}
+			return tmpl, docsTemplateName, nil
+		}
+		return tmpl, templateName, nil
+	}
+
+	tmpl, err = tmpl.ParseFiles(cfg.Output.Templates...)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse output templates: %w", err)
+	}
+
+	entry := cfg.Output.TemplateEntry
+	if isStringBlank(entry) {
+		entry = filepath.Base(cfg.Output.Templates[0])
+	}
+	if tmpl.Lookup(entry) == nil {
+		return nil, "", fmt.Errorf("output template entry %q is not defined by the output templates", entry)
+	}
+	return tmpl, entry, nil
+}
+
+// outputPath executes output.path_template for a package
+func outputPath(tmpl *template.Template, pkg *PackageModel, fileName string) (string, error) {
+	var path bytes.Buffer
+	data := struct {
+		Package  *PackageModel
+		FileName string
+	}{
+		Package:  pkg,
+		FileName: fileName,
+	}
+	if err := tmpl.Execute(&path, data); err != nil {
+		return "", fmt.Errorf("failed to execute output path template for %s: %w", pkg.Path, err)
+	}
+	return filepath.Clean(path.String()), nil
+}
+
+// renderPackageDoc executes output.package_doc for a package and returns it
+// as a gofmt formatted doc comment, ending with a new line
+func renderPackageDoc(tmpl *template.Template, pkg *PackageModel, packageName string) (string, error) {
+	var doc bytes.Buffer
+	data := struct {
+		Package     *PackageModel
+		PackageName string
+	}{
+		Package:     pkg,
+		PackageName: packageName,
+	}
+	if err := tmpl.Execute(&doc, data); err != nil {
+		return "", fmt.Errorf("failed to execute package doc template for %s: %w", pkg.Path, err)
+	}
+
+	var comment strings.Builder
+	for _, line := range strings.Split(strings.TrimSpace(doc.String()), "\n") {
+		line = strings.TrimRight(line, " \t")
+		if line == "" {
+			comment.WriteString("//\n")
+			continue
+		}
+		comment.WriteString("// " + line + "\n")
+	}
+
+	// The comment is formatted together with the package clause it documents
+	packageClause := "package " + packageName + "\n"
+	formatted, err := format.Source([]byte(comment.String() + packageClause))
+	if err != nil {
+		return "", fmt.Errorf("failed to format package doc for %s: %w", pkg.Path, err)
+	}
+	return strings.TrimSuffix(string(formatted), packageClause), nil
+}
+
+// mergeGeneratedRegion replaces the content between the merge markers of an
+// existing file with the generated declarations, taken from between the
+// markers of the generated content, leaving the rest intact. The imports of
+// the generated content missing from the file are added to it, and the
+// merged file is formatted with gofmt.
+func mergeGeneratedRegion(fileName string, existing []byte, generated []byte, output *ConfigOutput) error {
+	content := string(existing)
+	begin := strings.Index(content, mergeBeginMarker)
+	end := strings.Index(content, mergeEndMarker)
+	if begin < 0 || end < begin {
+		return fmt.Errorf("output file %s must contain the %q and %q markers", fileName, mergeBeginMarker, mergeEndMarker)
+	}
+
+	region := string(generated)
+	regionBegin := strings.Index(region, mergeBeginMarker)
+	regionEnd := strings.Index(region, mergeEndMarker)
+	if regionBegin < 0 || regionEnd < regionBegin {
+		return fmt.Errorf("generated content of %s must contain the %q and %q markers", fileName, mergeBeginMarker, mergeEndMarker)
+	}
+	region = region[regionBegin+len(mergeBeginMarker) : regionEnd]
+
+	merged := content[:begin+len(mergeBeginMarker)] + region + content[end:]
+	formatted, err := mergeImports(fileName, []byte(merged), generated)
+	if err != nil {
+		return err
+	}
+	return writeOutput(fileName, formatted, output)
+}
+
+// mergeImports adds the imports of the generated content missing from the
+// merged file and returns the file formatted with gofmt
+func mergeImports(fileName string, merged []byte, generated []byte) ([]byte, error) {
+	generatedFile, err := parser.ParseFile(token.NewFileSet(), fileName, generated, parser.ImportsOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse generated content of %s: %w", fileName, err)
+	}
+
+	fset := token.NewFileSet()
+	mergedFile, err := parser.ParseFile(fset, fileName, merged, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse merged output file %s: %w", fileName, err)
+	}
+	for _, spec := range generatedFile.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
 		if err != nil {
-			return fmt.Errorf("failed to execute template for %s: %w", fileName, err)
+			return nil, fmt.Errorf("invalid import %s in generated content of %s", spec.Path.Value, fileName)
+		}
+		// The template names every import, which is redundant when the name is
+		// the last element of the path
+		name := ""
+		if spec.Name != nil && spec.Name.Name != path.Base(importPath) {
+			name = spec.Name.Name
 		}
+		astutil.AddNamedImport(fset, mergedFile, name, importPath)
 	}
 
+	var formatted bytes.Buffer
+	if err := format.Node(&formatted, fset, mergedFile); err != nil {
+		return nil, fmt.Errorf("failed to format %s: %w", fileName, err)
+	}
+	return formatted.Bytes(), nil
+}
+
+// writeOutput writes the generated content to the file, unless it already
+// holds the same code once formatted and force isn't set, so unchanged files
+// keep their modification time. With output.goimports, unused imports are
+// removed and missing ones added first, and with output.indent spaces the
+// formatted code is indented with spaces.
+func writeOutput(fileName string, content []byte, output *ConfigOutput) error {
+	content, err := formatOutput(fileName, content, output)
+	if err != nil {
+		return err
+	}
+	if !output.isForce() {
+		if existing, err := os.ReadFile(fileName); err == nil && sameSource(existing, content) {
+			return nil
+		}
+	}
+	if err := os.WriteFile(fileName, content, 0644); err != nil {
+		return fmt.Errorf("failed to write output file %s: %w", fileName, err)
+	}
 	return nil
 }
+
+// formatOutput applies output.goimports and output.indent to the generated
+// content of a file
+func formatOutput(fileName string, content []byte, output *ConfigOutput) ([]byte, error) {
+	if output.isGoimports() {
+		processed, err := imports.Process(fileName, content, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to process imports of %s: %w", fileName, err)
+		}
+		content = processed
+	}
+	if spaces := output.indentSpaces(); spaces > 0 {
+		formatted, err := format.Source(content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to format %s: %w", fileName, err)
+		}
+		content = indentWithSpaces(formatted, spaces)
+	}
+	return content, nil
+}
+
+// indentWithSpaces replaces the leading tabs of every line with spaces. The
+// generated code has no multi-line raw strings whose content could change.
+func indentWithSpaces(content []byte, spaces int) []byte {
+	indent := strings.Repeat(" ", spaces)
+	lines := strings.SplitAfter(string(content), "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, "\t")
+		lines[i] = strings.Repeat(indent, len(line)-len(trimmed)) + trimmed
+	}
+	return []byte(strings.Join(lines, ""))
+}
+
+// sameSource reports whether both sources are equal after gofmt, comparing
+// the raw bytes when any of them can't be formatted
+func sameSource(a []byte, b []byte) bool {
+	formattedA, errA := format.Source(a)
+	formattedB, errB := format.Source(b)
+	if errA != nil || errB != nil {
+		return bytes.Equal(a, b)
+	}
+	return bytes.Equal(formattedA, formattedB)
+}
@@ -1,9 +1,17 @@
 package constago
 
 import (
+	"errors"
+	"go/format"
+	"go/parser"
+	"go/token"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -292,57 +300,46 @@ func (_struct *User) GetValueName() (strings.Builder) {
 	assert.Contains(t, generatedStr, expectedGetter)
 }
 
-func TestGenerate_MultiplePackages(t *testing.T) {
+func TestGenerate_DroppedGetterImports(t *testing.T) {
 	tempDir := t.TempDir()
 
-	// Create multiple packages
-	modelDir := filepath.Join(tempDir, "model")
-	serviceDir := filepath.Join(tempDir, "service")
-	require.NoError(t, os.MkdirAll(modelDir, 0755))
-	require.NoError(t, os.MkdirAll(serviceDir, 0755))
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
 
-	modelFile := filepath.Join(modelDir, "user.go")
-	modelContent := `package model
+import (
+	"strings"
+	"time"
+)
 
 type User struct {
-	Name string ` + "`json:\"name\"`" + `
-}
-`
-	require.NoError(t, os.WriteFile(modelFile, []byte(modelContent), 0644))
-
-	serviceFile := filepath.Join(serviceDir, "service.go")
-	serviceContent := `package service
-
-type Service struct {
-	Name string ` + "`json:\"name\"`" + `
+	Name      strings.Builder ` + "`json:\"name\" title:\"Name\"`" + `
+	CreatedAt time.Time       ` + "`json:\"created_at\"`" + `
 }
 `
-	require.NoError(t, os.WriteFile(serviceFile, []byte(serviceContent), 0644))
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
 
 	config := &Config{
 		Input: ConfigInput{
 			Dir: tempDir,
-			Struct: ConfigInputStruct{
-				Explicit:          boolPtr(false),
-				IncludeUnexported: boolPtr(false),
-			},
-			Field: ConfigInputField{
-				Explicit:          boolPtr(false),
-				IncludeUnexported: boolPtr(false),
-			},
 		},
 		Output: ConfigOutput{
-			FileName: "gen.go",
+			FileName: "imports_gen.go",
 		},
 		Elements: []ConfigTag{
 			{
-				Name: "json",
+				Name: "title",
 				Input: ConfigTagInput{
-					Mode:        InputModeTypeTagThenField,
-					TagPriority: []string{"json"},
+					Mode:        InputModeTypeTag,
+					TagPriority: []string{"title"},
 				},
-				Output: ConfigTagOutput{
-					Mode: OutputModeConstant,
+			},
+		},
+		Getters: []ConfigGetter{
+			{
+				Name:    "GetTitled",
+				Returns: []string{":value", "title"},
+				Output: ConfigGetterOutput{
+					Format: ConstantFormatPascal,
 				},
 			},
 		},
@@ -351,80 +348,113 @@ type Service struct {
 	err := Generate(config)
 	require.NoError(t, err)
 
-	// Check both output files were created
-	modelGen := filepath.Join(modelDir, "gen.go")
-	serviceGen := filepath.Join(serviceDir, "gen.go")
-
-	assert.FileExists(t, modelGen)
-	assert.FileExists(t, serviceGen)
-
-	modelGenerated, err := os.ReadFile(modelGen)
+	generated, err := os.ReadFile(filepath.Join(tempDir, "imports_gen.go"))
 	require.NoError(t, err)
-	assert.Contains(t, string(modelGenerated), `
-// Constants for User
-const (
-	JsonUserName = "name"
-)`)
+	generatedStr := string(generated)
 
-	serviceGenerated, err := os.ReadFile(serviceGen)
-	require.NoError(t, err)
-	assert.Contains(t, string(serviceGenerated), `
-// Constants for Service
-const (
-	JsonServiceName = "name"
-)`)
+	// The CreatedAt getter resolves time.Time but is dropped since the field
+	// has no title, so time must not be imported
+	assert.Contains(t, generatedStr, `strings "strings"`)
+	assert.Contains(t, generatedStr, "GetTitledName()")
+	assert.NotContains(t, generatedStr, "GetTitledCreatedAt")
+	assert.NotContains(t, generatedStr, `"time"`)
 }
 
-func TestGenerate_SkipEmptyPackages(t *testing.T) {
+func TestGenerate_EnvTagValues(t *testing.T) {
 	tempDir := t.TempDir()
 
-	// Create a package with no structs
-	emptyDir := filepath.Join(tempDir, "empty")
-	require.NoError(t, os.MkdirAll(emptyDir, 0755))
-	emptyFile := filepath.Join(emptyDir, "empty.go")
-	emptyContent := `package empty
+	testFile := filepath.Join(tempDir, "config.go")
+	content := `package main
 
-// No structs here
-var x = 1
+type Config struct {
+	DatabaseURL string ` + "`env:\"DATABASE_URL\"`" + `
+	Port        int    ` + "`env:\"HTTP_PORT,required\" envDefault:\"8080\"`" + `
+	Debug       bool
+}
 `
-	require.NoError(t, os.WriteFile(emptyFile, []byte(emptyContent), 0644))
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
 
-	// Create a package with structs
-	modelDir := filepath.Join(tempDir, "model")
-	require.NoError(t, os.MkdirAll(modelDir, 0755))
-	modelFile := filepath.Join(modelDir, "user.go")
-	modelContent := `package model
+	tests := []struct {
+		name      string
+		tagValues bool
+	}{
+		{name: "tag values untransformed", tagValues: false},
+		{name: "tag values transformed as is", tagValues: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{
+				Input: ConfigInput{
+					Dir: tempDir,
+				},
+				Output: ConfigOutput{
+					FileName: "env_gen.go",
+				},
+				Elements: []ConfigTag{
+					{
+						Name: "env",
+						Input: ConfigTagInput{
+							Mode:        InputModeTypeTag,
+							TagPriority: []string{"env"},
+						},
+						Output: ConfigTagOutput{
+							Transform: ConfigTagOutputTransform{
+								ValueCase: TransformCaseAsIs,
+								TagValues: boolPtr(tt.tagValues),
+							},
+						},
+					},
+				},
+			}
+
+			err := Generate(config)
+			require.NoError(t, err)
+
+			generated, err := os.ReadFile(filepath.Join(tempDir, "env_gen.go"))
+			require.NoError(t, err)
+			generatedStr := string(generated)
+
+			// Env var names are kept verbatim, without their options
+			assert.Contains(t, generatedStr, "\tEnvConfigDatabaseUrl = \"DATABASE_URL\"\n")
+			assert.Contains(t, generatedStr, "\tEnvConfigPort = \"HTTP_PORT\"\n")
+			assert.NotContains(t, generatedStr, "Debug")
+		})
+	}
+}
+
+func TestGenerate_RouteSegmentValues(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
 
 type User struct {
-	Name string ` + "`json:\"name\"`" + `
+	FirstName string
+	UserID    string
 }
 `
-	require.NoError(t, os.WriteFile(modelFile, []byte(modelContent), 0644))
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
 
 	config := &Config{
 		Input: ConfigInput{
 			Dir: tempDir,
-			Struct: ConfigInputStruct{
-				Explicit:          boolPtr(false),
-				IncludeUnexported: boolPtr(false),
-			},
-			Field: ConfigInputField{
-				Explicit:          boolPtr(false),
-				IncludeUnexported: boolPtr(false),
-			},
 		},
 		Output: ConfigOutput{
-			FileName: "gen.go",
+			FileName: "route_gen.go",
 		},
 		Elements: []ConfigTag{
 			{
-				Name: "json",
+				Name: "route",
 				Input: ConfigTagInput{
-					Mode:        InputModeTypeTagThenField,
-					TagPriority: []string{"json"},
+					Mode: InputModeTypeField,
 				},
 				Output: ConfigTagOutput{
 					Mode: OutputModeConstant,
+					Transform: ConfigTagOutputTransform{
+						ValueCase:      TransformCaseLower,
+						ValueSeparator: "-",
+					},
 				},
 			},
 		},
@@ -433,57 +463,37 @@ type User struct {
 	err := Generate(config)
 	require.NoError(t, err)
 
-	// Empty package should not have generated file
-	emptyGen := filepath.Join(emptyDir, "gen.go")
-	assert.NoFileExists(t, emptyGen)
-
-	// Model package should have generated file
-	modelGen := filepath.Join(modelDir, "gen.go")
-	assert.FileExists(t, modelGen)
-}
-
-func TestGenerate_InvalidConfig(t *testing.T) {
-	config := &Config{
-		Output: ConfigOutput{
-			FileName: "test.txt", // Invalid: should be .go
-		},
-	}
+	generated, err := os.ReadFile(filepath.Join(tempDir, "route_gen.go"))
+	require.NoError(t, err)
+	generatedStr := string(generated)
 
-	err := Generate(config)
-	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "failed to create config")
+	// Field names become kebab-case path segments
+	assert.Contains(t, generatedStr, "\tRouteUserFirstName = \"first-name\"\n")
+	assert.Contains(t, generatedStr, "\tRouteUserUserId = \"user-id\"\n")
 }
 
-func TestGenerate_NestedDirectories(t *testing.T) {
+func TestGenerate_AnnotateOptions(t *testing.T) {
 	tempDir := t.TempDir()
 
-	// Create deeply nested package
-	nestedDir := filepath.Join(tempDir, "a", "b", "c", "d")
-	require.NoError(t, os.MkdirAll(nestedDir, 0755))
-
-	nestedFile := filepath.Join(nestedDir, "nested.go")
-	nestedContent := `package d
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
 
-type Nested struct {
-	Value string ` + "`json:\"value\"`" + `
+type User struct {
+	Name  string ` + "`json:\"name\"`" + `
+	Email string ` + "`json:\"email,omitempty\"`" + `
+	Age   int    ` + "`json:\"age,omitempty,string\"`" + `
+	Phone string
 }
 `
-	require.NoError(t, os.WriteFile(nestedFile, []byte(nestedContent), 0644))
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
 
 	config := &Config{
 		Input: ConfigInput{
 			Dir: tempDir,
-			Struct: ConfigInputStruct{
-				Explicit:          boolPtr(false),
-				IncludeUnexported: boolPtr(false),
-			},
-			Field: ConfigInputField{
-				Explicit:          boolPtr(false),
-				IncludeUnexported: boolPtr(false),
-			},
 		},
 		Output: ConfigOutput{
-			FileName: "generated.go",
+			FileName:        "options_gen.go",
+			AnnotateOptions: boolPtr(true),
 		},
 		Elements: []ConfigTag{
 			{
@@ -492,9 +502,6 @@ type Nested struct {
 					Mode:        InputModeTypeTagThenField,
 					TagPriority: []string{"json"},
 				},
-				Output: ConfigTagOutput{
-					Mode: OutputModeConstant,
-				},
 			},
 		},
 	}
@@ -502,145 +509,307 @@ type Nested struct {
 	err := Generate(config)
 	require.NoError(t, err)
 
-	// Check nested directory has generated file
-	generatedFile := filepath.Join(nestedDir, "generated.go")
-	assert.FileExists(t, generatedFile)
-
-	generated, err := os.ReadFile(generatedFile)
+	generated, err := os.ReadFile(filepath.Join(tempDir, "options_gen.go"))
 	require.NoError(t, err)
-	generatedStr := string(generated)
-	expectedOutput := `
-// Constants for Nested
+
+	expected := `
 const (
-	JsonNestedValue = "value"
+	JsonUserName = "name"
+	JsonUserEmail = "email" // omitempty
+	JsonUserAge = "age" // omitempty,string
+	JsonUserPhone = "Phone"
 )`
-	assert.Contains(t, generatedStr, expectedOutput)
+	assert.Contains(t, string(generated), expected)
 }
 
-func TestGenerate_WithImportAliases(t *testing.T) {
+func TestGenerate_EmitSourceTag(t *testing.T) {
 	tempDir := t.TempDir()
 
 	testFile := filepath.Join(tempDir, "user.go")
 	content := `package main
 
 type User struct {
-	Name string ` + "`json:\"name\"`" + `
-	Age  int    ` + "`json:\"age\"`" + `
+	Name  string ` + "`json:\"name\" yaml:\"user_name\"`" + `
+	Email string ` + "`yaml:\"email,omitempty\"`" + `
+	Phone string
 }
 `
 	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
 
-	config := &Config{
-		Input: ConfigInput{
-			Dir: tempDir,
-			Struct: ConfigInputStruct{
-				Explicit:          boolPtr(false),
-				IncludeUnexported: boolPtr(false),
-			},
-			Field: ConfigInputField{
-				Explicit:          boolPtr(false),
-				IncludeUnexported: boolPtr(false),
-			},
+	tests := []struct {
+		name            string
+		annotateOptions bool
+		expected        string
+	}{
+		{
+			name: "source tag",
+			expected: `
+const (
+	SerialUserName = "name" // json tag
+	SerialUserEmail = "email" // yaml tag
+	SerialUserPhone = "Phone"
+)`,
 		},
-		Output: ConfigOutput{
-			FileName: "aliases_gen.go",
+		{
+			name:            "source tag with options",
+			annotateOptions: true,
+			expected: `
+const (
+	SerialUserName = "name" // json tag
+	SerialUserEmail = "email" // yaml tag (omitempty)
+	SerialUserPhone = "Phone"
+)`,
 		},
-		Elements: []ConfigTag{
-			{
-				Name: "json",
-				Input: ConfigTagInput{
-					Mode:        InputModeTypeTagThenField,
-					TagPriority: []string{"json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{
+				Input: ConfigInput{
+					Dir: tempDir,
 				},
-				Output: ConfigTagOutput{
-					Mode: OutputModeNone,
+				Output: ConfigOutput{
+					FileName:        "serial_gen.go",
+					EmitSourceTag:   boolPtr(true),
+					AnnotateOptions: boolPtr(tt.annotateOptions),
 				},
-			},
-		},
-		Getters: []ConfigGetter{
-			{
-				Name:    "GetValue",
-				Returns: []string{":value"},
-				Output: ConfigGetterOutput{
-					Format: ConstantFormatPascal,
+				Elements: []ConfigTag{
+					{
+						Name: "serial",
+						Input: ConfigTagInput{
+							Mode:        InputModeTypeTagThenField,
+							TagPriority: []string{"json", "yaml"},
+						},
+					},
 				},
-			},
-		},
-	}
-
-	err := Generate(config)
-	require.NoError(t, err)
+			}
 
-	outputFile := filepath.Join(tempDir, "aliases_gen.go")
-	assert.FileExists(t, outputFile)
+			err := Generate(config)
+			require.NoError(t, err)
 
-	generated, err := os.ReadFile(outputFile)
-	require.NoError(t, err)
-	generatedStr := string(generated)
-	expectedGetter := `
-// GetValueName returns the configured values for User
-func (_struct *User) GetValueName() (string) {
-	return  _struct.Name
-}`
-	assert.Contains(t, generatedStr, expectedGetter)
+			generated, err := os.ReadFile(filepath.Join(tempDir, "serial_gen.go"))
+			require.NoError(t, err)
+			assert.Contains(t, string(generated), tt.expected)
+		})
+	}
 }
 
-func TestGenerate_ComplexScenario(t *testing.T) {
+func TestGenerate_AggregateTo(t *testing.T) {
 	tempDir := t.TempDir()
 
-	testFile := filepath.Join(tempDir, "user.go")
-	content := `package main
+	files := map[string]string{
+		filepath.Join("model", "user.go"): `package model
 
 type User struct {
-	Name    string ` + "`json:\"name\" title:\"Full Name\" field:\"name_field\"`" + `
-	Email   string ` + "`json:\"email\" title:\"Email Address\"`" + `
-	Age     int    ` + "`json:\"age\"`" + `
-	Country string ` + "`json:\"country\" constago:\"include\"`" + `
+	Name string ` + "`json:\"name\" title:\"Name\"`" + `
 }
-`
-	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+`,
+		filepath.Join("billing", "invoice.go"): `package billing
+
+type Invoice struct {
+	Name  string ` + "`json:\"name\"`" + `
+	Total int    ` + "`json:\"total\"`" + `
+}
+`,
+	}
+	for name, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Join(tempDir, filepath.Dir(name)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644))
+	}
 
 	config := &Config{
 		Input: ConfigInput{
 			Dir: tempDir,
-			Struct: ConfigInputStruct{
-				Explicit:          boolPtr(false),
-				IncludeUnexported: boolPtr(false),
-			},
-			Field: ConfigInputField{
-				Explicit:          boolPtr(false),
-				IncludeUnexported: boolPtr(false),
-			},
 		},
 		Output: ConfigOutput{
-			FileName: "complex_gen.go",
+			FileName: "keys_gen.go",
 		},
 		Elements: []ConfigTag{
 			{
 				Name: "json",
 				Input: ConfigTagInput{
-					Mode:        InputModeTypeTagThenField,
+					Mode:        InputModeTypeTag,
 					TagPriority: []string{"json"},
 				},
 				Output: ConfigTagOutput{
-					Mode: OutputModeConstant,
+					AggregateTo: "keys",
 				},
 			},
 			{
 				Name: "title",
 				Input: ConfigTagInput{
-					Mode:        InputModeTypeTagThenField,
+					Mode:        InputModeTypeTag,
 					TagPriority: []string{"title"},
 				},
-				Output: ConfigTagOutput{
-					Mode: OutputModeConstant,
+			},
+		},
+	}
+
+	err := Generate(config)
+	require.NoError(t, err)
+
+	generated, err := os.ReadFile(filepath.Join(tempDir, "keys", "keys_gen.go"))
+	require.NoError(t, err)
+
+	// The json constants of both packages land in the keys package, qualified
+	// by the package they come from
+	expected := `package keys
+
+import (
+)
+// Constants for billing.Invoice
+const (
+	BillingJsonInvoiceName = "name"
+	BillingJsonInvoiceTotal = "total"
+)
+// Constants for model.User
+const (
+	ModelJsonUserName = "name"
+)`
+	assert.Contains(t, string(generated), expected)
+
+	// Elements not aggregated stay in their package
+	generated, err = os.ReadFile(filepath.Join(tempDir, "model", "keys_gen.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(generated), "\tTitleUserName = \"Name\"\n")
+	assert.NotContains(t, string(generated), "Json")
+	assert.NoFileExists(t, filepath.Join(tempDir, "billing", "keys_gen.go"))
+
+	// The aggregated file isn't scanned again on the next run
+	require.NoError(t, Generate(config))
+
+	// Packages with the same name can't be aggregated together
+	require.NoError(t, os.MkdirAll(filepath.Join(tempDir, "admin", "model"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "admin", "model", "user.go"), []byte(files[filepath.Join("model", "user.go")]), 0644))
+	err = Generate(config)
+	assert.ErrorContains(t, err, "packages "+filepath.ToSlash(filepath.Join(tempDir, "admin", "model"))+" and "+filepath.ToSlash(filepath.Join(tempDir, "model"))+" are both named model, so their constants can't be told apart in "+filepath.ToSlash(filepath.Join(tempDir, "keys"))+" (output.aggregate_to of element json)")
+}
+
+func TestGenerate_FlattenEmbedded(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "admin.go")
+	content := `package main
+
+type Meta struct {
+	Created string ` + "`json:\"created\"`" + `
+}
+
+type User struct {
+	Name string ` + "`json:\"name\"`" + `
+	*Meta
+}
+
+type Audit struct {
+	Name string ` + "`json:\"audit_name\"`" + `
+	By   string ` + "`json:\"by\"`" + `
+}
+
+type Admin struct {
+	*User
+	Audit
+	Role string ` + "`json:\"role\"`" + `
+}
+
+type Node struct {
+	*Node
+	Value string ` + "`json:\"value\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	generate := func(flatten bool) string {
+		config := &Config{
+			Input: ConfigInput{
+				Dir: tempDir,
+				Field: ConfigInputField{
+					FlattenEmbedded: boolPtr(flatten),
+				},
+			},
+			Output: ConfigOutput{
+				FileName: "embedded_gen.go",
+			},
+			Elements: []ConfigTag{
+				{
+					Name: "json",
+					Input: ConfigTagInput{
+						Mode:        InputModeTypeTag,
+						TagPriority: []string{"json"},
+					},
+				},
+			},
+		}
+		require.NoError(t, Generate(config))
+
+		generated, err := os.ReadFile(filepath.Join(tempDir, "embedded_gen.go"))
+		require.NoError(t, err)
+		return string(generated)
+	}
+
+	// Fields are promoted through pointer and value embeds, Name being
+	// ambiguous at the same depth
+	expectedAdmin := `
+// Constants for Admin
+const (
+	JsonAdminCreated = "created"
+	JsonAdminBy = "by"
+	JsonAdminRole = "role"
+)`
+	generatedStr := generate(true)
+	assert.Contains(t, generatedStr, expectedAdmin)
+	assert.Contains(t, generatedStr, "\tJsonUserCreated = \"created\"\n")
+	assert.Contains(t, generatedStr, "\tJsonNodeValue = \"value\"\n")
+
+	// Embedded structs aren't flattened by default
+	expectedAdmin = `
+// Constants for Admin
+const (
+	JsonAdminRole = "role"
+)`
+	generatedStr = generate(false)
+	assert.Contains(t, generatedStr, expectedAdmin)
+	assert.NotContains(t, generatedStr, "JsonUserCreated")
+}
+
+func TestGenerate_StripStructSuffix(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type UserModel struct {
+	Name string ` + "`json:\"name\" xml:\"name\"`" + `
+}
+
+type Model struct {
+	ID string ` + "`json:\"id\" xml:\"id\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config := &Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+			Struct: ConfigInputStruct{
+				StripSuffix: "Model",
+			},
+		},
+		Output: ConfigOutput{
+			FileName: "strip_gen.go",
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "json",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTag,
+					TagPriority: []string{"json"},
 				},
 			},
 			{
-				Name: "field",
+				Name: "xml",
 				Input: ConfigTagInput{
 					Mode:        InputModeTypeTag,
-					TagPriority: []string{"field"},
+					TagPriority: []string{"xml"},
 				},
 				Output: ConfigTagOutput{
 					Mode: OutputModeStruct,
@@ -649,17 +818,91 @@ type User struct {
 		},
 		Getters: []ConfigGetter{
 			{
-				Name:    "GetAll",
-				Returns: []string{"json", "title", "field"},
-				Output: ConfigGetterOutput{
-					Format: ConstantFormatPascal,
-				},
+				Name:    "Json",
+				Returns: []string{"json"},
+			},
+		},
+	}
+
+	err := Generate(config)
+	require.NoError(t, err)
+
+	generated, err := os.ReadFile(filepath.Join(tempDir, "strip_gen.go"))
+	require.NoError(t, err)
+	generatedStr := string(generated)
+
+	// Identifiers use the stripped name, the receiver keeps the struct name
+	assert.Contains(t, generatedStr, "// Constants for UserModel\n")
+	assert.Contains(t, generatedStr, "\tJsonUserName = \"name\"\n")
+	assert.Contains(t, generatedStr, "var XmlUser = struct {")
+	assert.Contains(t, generatedStr, "func (_struct *UserModel) JsonName() (string) {")
+	assert.NotContains(t, generatedStr, "JsonUserModelName")
+
+	// Names left empty by stripping are kept whole
+	assert.Contains(t, generatedStr, "\tJsonModelId = \"id\"\n")
+
+	// Structs left with the same name would declare every identifier twice
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "account.go"), []byte(`package main
+
+type User struct {
+	Email string `+"`json:\"email\" xml:\"email\"`"+`
+}
+`), 0644))
+	err = Generate(config)
+	assert.EqualError(t, err, "failed to build model: structs User and UserModel of "+tempDir+" are both named User in generated identifiers (input.struct.strip_prefix and strip_suffix)")
+}
+
+func TestGenerate_MultiplePackages(t *testing.T) {
+	tempDir := t.TempDir()
+
+	// Create multiple packages
+	modelDir := filepath.Join(tempDir, "model")
+	serviceDir := filepath.Join(tempDir, "service")
+	require.NoError(t, os.MkdirAll(modelDir, 0755))
+	require.NoError(t, os.MkdirAll(serviceDir, 0755))
+
+	modelFile := filepath.Join(modelDir, "user.go")
+	modelContent := `package model
+
+type User struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(modelFile, []byte(modelContent), 0644))
+
+	serviceFile := filepath.Join(serviceDir, "service.go")
+	serviceContent := `package service
+
+type Service struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(serviceFile, []byte(serviceContent), 0644))
+
+	config := &Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+			Struct: ConfigInputStruct{
+				Explicit:          boolPtr(false),
+				IncludeUnexported: boolPtr(false),
+			},
+			Field: ConfigInputField{
+				Explicit:          boolPtr(false),
+				IncludeUnexported: boolPtr(false),
 			},
+		},
+		Output: ConfigOutput{
+			FileName: "gen.go",
+		},
+		Elements: []ConfigTag{
 			{
-				Name:    "GetValue",
-				Returns: []string{":value", "json"},
-				Output: ConfigGetterOutput{
-					Format: ConstantFormatPascal,
+				Name: "json",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTagThenField,
+					TagPriority: []string{"json"},
+				},
+				Output: ConfigTagOutput{
+					Mode: OutputModeConstant,
 				},
 			},
 		},
@@ -668,34 +911,3023 @@ type User struct {
 	err := Generate(config)
 	require.NoError(t, err)
 
-	outputFile := filepath.Join(tempDir, "complex_gen.go")
-	assert.FileExists(t, outputFile)
+	// Check both output files were created
+	modelGen := filepath.Join(modelDir, "gen.go")
+	serviceGen := filepath.Join(serviceDir, "gen.go")
 
-	generated, err := os.ReadFile(outputFile)
-	require.NoError(t, err)
-	generatedStr := string(generated)
+	assert.FileExists(t, modelGen)
+	assert.FileExists(t, serviceGen)
 
-	expectedBlock := `
+	modelGenerated, err := os.ReadFile(modelGen)
+	require.NoError(t, err)
+	assert.Contains(t, string(modelGenerated), `
 // Constants for User
 const (
 	JsonUserName = "name"
-	TitleUserName = "Full Name"
-	JsonUserEmail = "email"
-	TitleUserEmail = "Email Address"
-	JsonUserAge = "age"
-	TitleUserAge = "Age"
-	JsonUserCountry = "country"
-	TitleUserCountry = "Country"
-)
-// FieldUser contains field constants for User
-var FieldUser = struct {
-	Name string
-}{
-	Name: "name_field",
+)`)
+
+	serviceGenerated, err := os.ReadFile(serviceGen)
+	require.NoError(t, err)
+	assert.Contains(t, string(serviceGenerated), `
+// Constants for Service
+const (
+	JsonServiceName = "name"
+)`)
 }
-// GetAllName returns the configured values for User
-func (_struct *User) GetAllName() (string, string, string) {
-	return "name", "Full Name", "name_field"
-}`
-	assert.Contains(t, generatedStr, expectedBlock)
+
+func TestGenerate_SkipEmptyPackages(t *testing.T) {
+	tempDir := t.TempDir()
+
+	// Create a package with no structs
+	emptyDir := filepath.Join(tempDir, "empty")
+	require.NoError(t, os.MkdirAll(emptyDir, 0755))
+	emptyFile := filepath.Join(emptyDir, "empty.go")
+	emptyContent := `package empty
+
+// No structs here
+var x = 1
+`
+	require.NoError(t, os.WriteFile(emptyFile, []byte(emptyContent), 0644))
+
+	// Create a package with structs
+	modelDir := filepath.Join(tempDir, "model")
+	require.NoError(t, os.MkdirAll(modelDir, 0755))
+	modelFile := filepath.Join(modelDir, "user.go")
+	modelContent := `package model
+
+type User struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(modelFile, []byte(modelContent), 0644))
+
+	config := &Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+			Struct: ConfigInputStruct{
+				Explicit:          boolPtr(false),
+				IncludeUnexported: boolPtr(false),
+			},
+			Field: ConfigInputField{
+				Explicit:          boolPtr(false),
+				IncludeUnexported: boolPtr(false),
+			},
+		},
+		Output: ConfigOutput{
+			FileName: "gen.go",
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "json",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTagThenField,
+					TagPriority: []string{"json"},
+				},
+				Output: ConfigTagOutput{
+					Mode: OutputModeConstant,
+				},
+			},
+		},
+	}
+
+	err := Generate(config)
+	require.NoError(t, err)
+
+	// Empty package should not have generated file
+	emptyGen := filepath.Join(emptyDir, "gen.go")
+	assert.NoFileExists(t, emptyGen)
+
+	// Model package should have generated file
+	modelGen := filepath.Join(modelDir, "gen.go")
+	assert.FileExists(t, modelGen)
+}
+
+func TestGenerate_InvalidConfig(t *testing.T) {
+	config := &Config{
+		Output: ConfigOutput{
+			FileName: "test.txt", // Invalid: should be .go
+		},
+	}
+
+	err := Generate(config)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to create config")
+}
+
+func TestGenerate_NestedDirectories(t *testing.T) {
+	tempDir := t.TempDir()
+
+	// Create deeply nested package
+	nestedDir := filepath.Join(tempDir, "a", "b", "c", "d")
+	require.NoError(t, os.MkdirAll(nestedDir, 0755))
+
+	nestedFile := filepath.Join(nestedDir, "nested.go")
+	nestedContent := `package d
+
+type Nested struct {
+	Value string ` + "`json:\"value\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(nestedFile, []byte(nestedContent), 0644))
+
+	config := &Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+			Struct: ConfigInputStruct{
+				Explicit:          boolPtr(false),
+				IncludeUnexported: boolPtr(false),
+			},
+			Field: ConfigInputField{
+				Explicit:          boolPtr(false),
+				IncludeUnexported: boolPtr(false),
+			},
+		},
+		Output: ConfigOutput{
+			FileName: "generated.go",
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "json",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTagThenField,
+					TagPriority: []string{"json"},
+				},
+				Output: ConfigTagOutput{
+					Mode: OutputModeConstant,
+				},
+			},
+		},
+	}
+
+	err := Generate(config)
+	require.NoError(t, err)
+
+	// Check nested directory has generated file
+	generatedFile := filepath.Join(nestedDir, "generated.go")
+	assert.FileExists(t, generatedFile)
+
+	generated, err := os.ReadFile(generatedFile)
+	require.NoError(t, err)
+	generatedStr := string(generated)
+	expectedOutput := `
+// Constants for Nested
+const (
+	JsonNestedValue = "value"
+)`
+	assert.Contains(t, generatedStr, expectedOutput)
+}
+
+func TestGenerate_WithImportAliases(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type User struct {
+	Name string ` + "`json:\"name\"`" + `
+	Age  int    ` + "`json:\"age\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config := &Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+			Struct: ConfigInputStruct{
+				Explicit:          boolPtr(false),
+				IncludeUnexported: boolPtr(false),
+			},
+			Field: ConfigInputField{
+				Explicit:          boolPtr(false),
+				IncludeUnexported: boolPtr(false),
+			},
+		},
+		Output: ConfigOutput{
+			FileName: "aliases_gen.go",
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "json",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTagThenField,
+					TagPriority: []string{"json"},
+				},
+				Output: ConfigTagOutput{
+					Mode: OutputModeNone,
+				},
+			},
+		},
+		Getters: []ConfigGetter{
+			{
+				Name:    "GetValue",
+				Returns: []string{":value"},
+				Output: ConfigGetterOutput{
+					Format: ConstantFormatPascal,
+				},
+			},
+		},
+	}
+
+	err := Generate(config)
+	require.NoError(t, err)
+
+	outputFile := filepath.Join(tempDir, "aliases_gen.go")
+	assert.FileExists(t, outputFile)
+
+	generated, err := os.ReadFile(outputFile)
+	require.NoError(t, err)
+	generatedStr := string(generated)
+	expectedGetter := `
+// GetValueName returns the configured values for User
+func (_struct *User) GetValueName() (string) {
+	return  _struct.Name
+}`
+	assert.Contains(t, generatedStr, expectedGetter)
+}
+
+func TestGenerate_ComplexScenario(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type User struct {
+	Name    string ` + "`json:\"name\" title:\"Full Name\" field:\"name_field\"`" + `
+	Email   string ` + "`json:\"email\" title:\"Email Address\"`" + `
+	Age     int    ` + "`json:\"age\"`" + `
+	Country string ` + "`json:\"country\" constago:\"include\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config := &Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+			Struct: ConfigInputStruct{
+				Explicit:          boolPtr(false),
+				IncludeUnexported: boolPtr(false),
+			},
+			Field: ConfigInputField{
+				Explicit:          boolPtr(false),
+				IncludeUnexported: boolPtr(false),
+			},
+		},
+		Output: ConfigOutput{
+			FileName: "complex_gen.go",
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "json",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTagThenField,
+					TagPriority: []string{"json"},
+				},
+				Output: ConfigTagOutput{
+					Mode: OutputModeConstant,
+				},
+			},
+			{
+				Name: "title",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTagThenField,
+					TagPriority: []string{"title"},
+				},
+				Output: ConfigTagOutput{
+					Mode: OutputModeConstant,
+				},
+			},
+			{
+				Name: "field",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTag,
+					TagPriority: []string{"field"},
+				},
+				Output: ConfigTagOutput{
+					Mode: OutputModeStruct,
+				},
+			},
+		},
+		Getters: []ConfigGetter{
+			{
+				Name:    "GetAll",
+				Returns: []string{"json", "title", "field"},
+				Output: ConfigGetterOutput{
+					Format: ConstantFormatPascal,
+				},
+			},
+			{
+				Name:    "GetValue",
+				Returns: []string{":value", "json"},
+				Output: ConfigGetterOutput{
+					Format: ConstantFormatPascal,
+				},
+			},
+		},
+	}
+
+	err := Generate(config)
+	require.NoError(t, err)
+
+	outputFile := filepath.Join(tempDir, "complex_gen.go")
+	assert.FileExists(t, outputFile)
+
+	generated, err := os.ReadFile(outputFile)
+	require.NoError(t, err)
+	generatedStr := string(generated)
+
+	expectedBlock := `
+// Constants for User
+const (
+	JsonUserName = "name"
+	TitleUserName = "Full Name"
+	JsonUserEmail = "email"
+	TitleUserEmail = "Email Address"
+	JsonUserAge = "age"
+	TitleUserAge = "Age"
+	JsonUserCountry = "country"
+	TitleUserCountry = "Country"
+)
+// FieldUser contains field constants for User
+var FieldUser = struct {
+	Name string
+}{
+	Name: "name_field",
+}
+// GetAllName returns the configured values for User
+func (_struct *User) GetAllName() (string, string, string) {
+	return "name", "Full Name", "name_field"
+}`
+	assert.Contains(t, generatedStr, expectedBlock)
+}
+
+func TestGenerate_TypedKeyConstants(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type User struct {
+	Name string ` + "`json:\"name\"`" + `
+	Age  int    ` + "`json:\"age\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config := &Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Output: ConfigOutput{
+			FileName: "typed_gen.go",
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "json",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTagThenField,
+					TagPriority: []string{"json"},
+				},
+				Output: ConfigTagOutput{
+					Mode:     OutputModeConstant,
+					TypedKey: boolPtr(true),
+				},
+			},
+		},
+	}
+
+	err := Generate(config)
+	require.NoError(t, err)
+
+	generated, err := os.ReadFile(filepath.Join(tempDir, "typed_gen.go"))
+	require.NoError(t, err)
+	generatedStr := string(generated)
+
+	expectedOutput := `
+// JsonUserKey is the key type for User fields
+type JsonUserKey string
+// Constants for User
+const (
+	JsonUserKeyName JsonUserKey = "name"
+	JsonUserKeyAge JsonUserKey = "age"
+)`
+	assert.Contains(t, generatedStr, expectedOutput)
+}
+
+func TestGenerate_TypedKeyValues(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type User struct {
+	Name  string ` + "`json:\"name\"`" + `
+	Age   int    ` + "`json:\"age\"`" + `
+	email string ` + "`json:\"email\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config := &Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+			Field: ConfigInputField{
+				IncludeUnexported: boolPtr(true),
+			},
+		},
+		Output: ConfigOutput{
+			FileName:        "typed_gen.go",
+			SplitVisibility: boolPtr(true),
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "json",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTagThenField,
+					TagPriority: []string{"json"},
+				},
+				Output: ConfigTagOutput{
+					Mode:       OutputModeConstant,
+					TypedKey:   boolPtr(true),
+					EmitValues: boolPtr(true),
+				},
+			},
+		},
+	}
+
+	err := Generate(config)
+	require.NoError(t, err)
+
+	generated, err := os.ReadFile(filepath.Join(tempDir, "typed_gen.go"))
+	require.NoError(t, err)
+	generatedStr := string(generated)
+
+	// Values lists every constant of the key type, unexported ones included
+	expectedOutput := `
+// Values returns all the JsonUserKey values
+func (JsonUserKey) Values() []JsonUserKey {
+	return []JsonUserKey{
+		JsonUserKeyName,
+		JsonUserKeyAge,
+		jsonUserKeyEmail,
+	}
+}`
+	assert.Contains(t, generatedStr, expectedOutput)
+
+	_, err = parser.ParseFile(token.NewFileSet(), "typed_gen.go", generated, 0)
+	assert.NoError(t, err)
+}
+
+func TestGenerate_PackageName(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package model
+
+type User struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config := &Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Output: ConfigOutput{
+			FileName:    "constants_gen_test.go",
+			PackageName: "model_test",
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "json",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTagThenField,
+					TagPriority: []string{"json"},
+				},
+			},
+		},
+	}
+
+	err := Generate(config)
+	require.NoError(t, err)
+
+	generated, err := os.ReadFile(filepath.Join(tempDir, "constants_gen_test.go"))
+	require.NoError(t, err)
+	generatedStr := string(generated)
+
+	assert.Contains(t, generatedStr, "\npackage model_test\n")
+	assert.Contains(t, generatedStr, "JsonUserName = \"name\"")
+
+	// Only the external test package of the sources can live next to them
+	content = strings.Replace(content, "package model", "package account", 1)
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+	err = Generate(config)
+	assert.ErrorContains(t, err, "output.package_name model_test can't be written to "+tempDir+", which holds package account, only account_test can")
+}
+
+func TestGenerate_EmitSourceInfo(t *testing.T) {
+	tempDir := t.TempDir()
+
+	require.NoError(t, os.MkdirAll(filepath.Join(tempDir, "model"), 0755))
+	testFile := filepath.Join(tempDir, "model", "user.go")
+	content := `package model
+
+type User struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config := &Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Output: ConfigOutput{
+			FileName:       "constants_gen.go",
+			EmitSourceInfo: boolPtr(true),
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "json",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTagThenField,
+					TagPriority: []string{"json"},
+				},
+			},
+		},
+	}
+
+	err := Generate(config)
+	require.NoError(t, err)
+
+	generated, err := os.ReadFile(filepath.Join(tempDir, "model", "constants_gen.go"))
+	require.NoError(t, err)
+
+	expectedOutput := `
+// User is declared in model/user.go:3
+// Constants for User
+const (
+	JsonUserName = "name"
+)`
+	assert.Contains(t, string(generated), expectedOutput)
+}
+
+func TestGenerate_DeterministicOrderAcrossFiles(t *testing.T) {
+	tempDir := t.TempDir()
+
+	files := map[string]string{
+		"b_user.go": `package main
+
+type User struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+`,
+		"a_company.go": `package main
+
+type Company struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+`,
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644))
+	}
+
+	var previous string
+	for i := 0; i < 5; i++ {
+		config := &Config{
+			Input: ConfigInput{
+				Dir: tempDir,
+			},
+			Output: ConfigOutput{
+				FileName: "constants_gen.go",
+			},
+			Elements: []ConfigTag{
+				{
+					Name: "json",
+					Input: ConfigTagInput{
+						Mode:        InputModeTypeTagThenField,
+						TagPriority: []string{"json"},
+					},
+				},
+			},
+		}
+		require.NoError(t, Generate(config))
+
+		generated, err := os.ReadFile(filepath.Join(tempDir, "constants_gen.go"))
+		require.NoError(t, err)
+		generatedStr := string(generated)
+
+		// Structs are emitted following the sorted order of their files
+		companyIndex := strings.Index(generatedStr, "// Constants for Company")
+		userIndex := strings.Index(generatedStr, "// Constants for User")
+		require.NotEqual(t, -1, companyIndex)
+		require.NotEqual(t, -1, userIndex)
+		assert.Less(t, companyIndex, userIndex)
+
+		if previous != "" {
+			assert.Equal(t, previous, generatedStr)
+		}
+		previous = generatedStr
+	}
+}
+
+func TestGenerate_GettersPromoteNone(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type User struct {
+	Name string ` + "`json:\"name\" title:\"Name\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config := &Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Output: ConfigOutput{
+			FileName: "getters_gen.go",
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "title",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTagThenField,
+					TagPriority: []string{"title"},
+				},
+				Output: ConfigTagOutput{
+					Mode: OutputModeNone,
+				},
+			},
+		},
+		Getters: []ConfigGetter{
+			{
+				Name:    "GetTitle",
+				Returns: []string{"title"},
+				Output: ConfigGetterOutput{
+					PromoteNone: boolPtr(true),
+				},
+			},
+		},
+	}
+
+	err := Generate(config)
+	require.NoError(t, err)
+
+	generated, err := os.ReadFile(filepath.Join(tempDir, "getters_gen.go"))
+	require.NoError(t, err)
+	generatedStr := string(generated)
+
+	expectedOutput := `
+// Constants for User
+const (
+	TitleUserName = "Name"
+)
+// GetTitleName returns the configured values for User
+func (_struct *User) GetTitleName() (string) {
+	return TitleUserName
+}`
+	assert.Contains(t, generatedStr, expectedOutput)
+}
+
+func TestGenerate_GettersNoneName(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type User struct {
+	Name string ` + "`title:\"Full Name\" db:\"name\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config := &Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Output: ConfigOutput{
+			FileName: "getters_gen.go",
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "title",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTag,
+					TagPriority: []string{"title"},
+				},
+				Output: ConfigTagOutput{
+					Mode:     OutputModeNone,
+					NoneName: "label",
+				},
+			},
+			{
+				Name: "db",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTag,
+					TagPriority: []string{"db"},
+				},
+				Output: ConfigTagOutput{
+					Mode: OutputModeNone,
+				},
+			},
+		},
+		Getters: []ConfigGetter{
+			{
+				Name:    "Meta",
+				Returns: []string{"title", "db"},
+			},
+		},
+	}
+
+	err := Generate(config)
+	require.NoError(t, err)
+
+	generated, err := os.ReadFile(filepath.Join(tempDir, "getters_gen.go"))
+	require.NoError(t, err)
+
+	// Each none value is labeled, by none_name or else the element name
+	expectedOutput := `
+func (_struct *User) MetaName() (string, string) {
+	return "Full Name" /* label */, "name" /* db */
+}`
+	assert.Contains(t, string(generated), expectedOutput)
+}
+
+func TestGenerate_GettersResultNames(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type User struct {
+	Name string ` + "`json:\"name\" title:\"Name\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config := &Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Output: ConfigOutput{
+			FileName: "getters_gen.go",
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "json",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTagThenField,
+					TagPriority: []string{"json"},
+				},
+			},
+			{
+				Name: "title",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTagThenField,
+					TagPriority: []string{"title"},
+				},
+			},
+		},
+		Getters: []ConfigGetter{
+			{
+				Name:    "Get",
+				Returns: []string{"json", "title"},
+				Output: ConfigGetterOutput{
+					ResultNames: []string{"json", "title"},
+				},
+			},
+		},
+	}
+
+	err := Generate(config)
+	require.NoError(t, err)
+
+	generated, err := os.ReadFile(filepath.Join(tempDir, "getters_gen.go"))
+	require.NoError(t, err)
+
+	expectedOutput := `
+func (_struct *User) GetName() (json string, title string) {
+	return "name", "Name"
+}`
+	assert.Contains(t, string(generated), expectedOutput)
+}
+
+func TestGenerate_SingleConstBlock(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type User struct {
+	Name string ` + "`json:\"name\"`" + `
+	Age  int    ` + "`json:\"age\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config := &Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Output: ConfigOutput{
+			FileName:   "constants_gen.go",
+			ConstBlock: ConstBlockSingle,
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "json",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTagThenField,
+					TagPriority: []string{"json"},
+				},
+			},
+		},
+	}
+
+	err := Generate(config)
+	require.NoError(t, err)
+
+	generated, err := os.ReadFile(filepath.Join(tempDir, "constants_gen.go"))
+	require.NoError(t, err)
+	generatedStr := string(generated)
+
+	expectedOutput := `
+// Constants for User
+const JsonUserName = "name"
+const JsonUserAge = "age"`
+	assert.Contains(t, generatedStr, expectedOutput)
+	assert.NotContains(t, generatedStr, "const (")
+}
+
+func TestGenerate_VarDeclaration(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type User struct {
+	Name string ` + "`json:\"name\"`" + `
+	Age  int    ` + "`json:\"age\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config := &Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Output: ConfigOutput{
+			FileName:    "constants_gen.go",
+			Declaration: DeclarationVar,
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "json",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTagThenField,
+					TagPriority: []string{"json"},
+				},
+			},
+		},
+	}
+
+	err := Generate(config)
+	require.NoError(t, err)
+
+	generated, err := os.ReadFile(filepath.Join(tempDir, "constants_gen.go"))
+	require.NoError(t, err)
+	generatedStr := string(generated)
+
+	expectedOutput := `
+// Constants for User
+var (
+	JsonUserName = "name"
+	JsonUserAge = "age"
+)`
+	assert.Contains(t, generatedStr, expectedOutput)
+	assert.NotContains(t, generatedStr, "const (")
+}
+
+func TestGenerate_MapVarKeys(t *testing.T) {
+	tempDir := t.TempDir()
+
+	// The file declares no struct, so it's only read for its map variables
+	testFile := filepath.Join(tempDir, "colors.go")
+	content := `package main
+
+var colors = map[string]int{
+	"red":       1,
+	"dark-blue": 2,
+}
+
+var (
+	codes        = map[int]string{1: "one"}
+	DefaultSizes = map[string]string{"small": "s"}
+)
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config := &Config{
+		Input: ConfigInput{
+			Dir:            tempDir,
+			FastSkip:       boolPtr(true),
+			IncludeMapVars: boolPtr(true),
+		},
+		Output: ConfigOutput{
+			FileName: "constants_gen.go",
+		},
+	}
+
+	err := Generate(config)
+	require.NoError(t, err)
+
+	generated, err := os.ReadFile(filepath.Join(tempDir, "constants_gen.go"))
+	require.NoError(t, err)
+	generatedStr := string(generated)
+
+	expectedOutput := `
+// Keys of the colors map
+const (
+	ColorsRed = "red"
+	ColorsDarkBlue = "dark-blue"
+)
+// Keys of the DefaultSizes map
+const (
+	DefaultSizesSmall = "small"
+)`
+	assert.Contains(t, generatedStr, expectedOutput)
+	assert.NotContains(t, generatedStr, "codes")
+
+	// Keys of maps whose names differ in case give the same constant names
+	content += `
+var Colors = map[string]int{"Red": 1}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+	err = Generate(config)
+	assert.ErrorContains(t, err, `constant ColorsRed of map Colors collides with the one of map colors in `+tempDir+`, with values "Red" and "red"`)
+
+	// And with the constants of the structs
+	content = strings.Replace(content, "var Colors =", "var JsonColors =", 1) + `
+type Colors struct {
+	Red string ` + "`json:\"red\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+	config.Elements = []ConfigTag{{Name: "json"}}
+	err = Generate(config)
+	assert.ErrorContains(t, err, `constant JsonColorsRed of map JsonColors collides with the one of struct Colors in `+tempDir+`, with values "Red" and "red"`)
+}
+
+func TestGenerate_IdentifierCase(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type User struct {
+	FirstName string ` + "`json:\"first_name\" title:\"First name\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config := &Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Output: ConfigOutput{
+			FileName:       "constants_gen.go",
+			IdentifierCase: ConstantFormatSnakeUpper,
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "json",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTagThenField,
+					TagPriority: []string{"json"},
+				},
+				Output: ConfigTagOutput{
+					TypedKey: boolPtr(true),
+				},
+			},
+			{
+				Name: "title",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTagThenField,
+					TagPriority: []string{"title"},
+				},
+				Output: ConfigTagOutput{
+					Mode: OutputModeStruct,
+				},
+			},
+		},
+		Getters: []ConfigGetter{
+			{
+				Name:    "GetField",
+				Returns: []string{"json"},
+			},
+		},
+	}
+
+	err := Generate(config)
+	require.NoError(t, err)
+
+	generated, err := os.ReadFile(filepath.Join(tempDir, "constants_gen.go"))
+	require.NoError(t, err)
+	generatedStr := string(generated)
+
+	assert.Contains(t, generatedStr, "type JSON_USER_KEY string")
+	assert.Contains(t, generatedStr, `JSON_USER_KEY_FIRST_NAME JSON_USER_KEY = "first_name"`)
+	assert.Contains(t, generatedStr, "var TITLE_USER = struct {\n\tFIRST_NAME string\n}")
+	assert.Contains(t, generatedStr, `FIRST_NAME: "First name"`)
+	assert.Contains(t, generatedStr, "func (_struct *User) GET_FIELD_FIRST_NAME() (string)")
+
+	_, err = parser.ParseFile(token.NewFileSet(), "constants_gen.go", generated, 0)
+	assert.NoError(t, err)
+
+	// Identifiers differing only in case can't be folded to the same name
+	config.Elements = append(config.Elements, ConfigTag{
+		Name: "js",
+		Input: ConfigTagInput{
+			Mode:        InputModeTypeTagThenField,
+			TagPriority: []string{"json"},
+		},
+		Output: ConfigTagOutput{
+			Format: ConfigTagOutputFormat{
+				Prefix:        "Json_",
+				PrefixLiteral: boolPtr(true),
+			},
+		},
+	})
+	config.Elements[0].Output.TypedKey = boolPtr(false)
+	err = Generate(config)
+	assert.ErrorContains(t, err, "identifiers JsonUserFirstName and Json_UserFirstName in "+tempDir+" are both formatted as JSON_USER_FIRST_NAME (output.identifier_case)")
+}
+
+func TestGenerate_GetterErrorReturn(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type User struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config := &Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Output: ConfigOutput{
+			FileName: "constants_gen.go",
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "json",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTagThenField,
+					TagPriority: []string{"json"},
+				},
+			},
+		},
+		Getters: []ConfigGetter{
+			{
+				Name:    "Get",
+				Returns: []string{":value"},
+				Output: ConfigGetterOutput{
+					Suffix:      "Validated",
+					ErrorReturn: boolPtr(true),
+					ErrorExpr:   `validate("{{ .Struct }}.{{ .Field }}", {{ .Receiver }}.{{ .Field }})`,
+				},
+			},
+			{
+				Name:    "Key",
+				Returns: []string{"json"},
+				Output: ConfigGetterOutput{
+					ResultNames: []string{"key"},
+					ErrorReturn: boolPtr(true),
+				},
+			},
+		},
+	}
+
+	err := Generate(config)
+	require.NoError(t, err)
+
+	generated, err := os.ReadFile(filepath.Join(tempDir, "constants_gen.go"))
+	require.NoError(t, err)
+	generatedStr := string(generated)
+
+	expectedOutput := `
+func (_struct *User) GetNameValidated() (string, error) {
+	return  _struct.Name, validate("User.Name", _struct.Name)
+}`
+	assert.Contains(t, generatedStr, expectedOutput)
+
+	// Named results name the error too
+	expectedOutput = `
+func (_struct *User) KeyName() (key string, err error) {
+	return "name", nil
+}`
+	assert.Contains(t, generatedStr, expectedOutput)
+}
+
+func TestGenerate_GetterReceiverType(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type User struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+
+type UserView struct {
+	*User
+}
+
+type UserAdmin struct {
+	Role string ` + "`json:\"role\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config := &Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Output: ConfigOutput{
+			FileName: "constants_gen.go",
+		},
+		Elements: []ConfigTag{
+			{Name: "json"},
+		},
+		Getters: []ConfigGetter{
+			{
+				Name:            "Get",
+				Returns:         []string{":value", "json"},
+				StructsMatching: "^User$",
+				Output: ConfigGetterOutput{
+					ReceiverType: "UserView",
+				},
+			},
+		},
+	}
+
+	err := Generate(config)
+	require.NoError(t, err)
+
+	generated, err := os.ReadFile(filepath.Join(tempDir, "constants_gen.go"))
+	require.NoError(t, err)
+	generatedStr := string(generated)
+
+	// The field is reached through the embed
+	expectedOutput := `
+func (_struct *UserView) GetName() (string, string) {
+	return  _struct.Name, "name"
+}`
+	assert.Contains(t, generatedStr, expectedOutput)
+	assert.NotContains(t, generatedStr, "func (_struct *User) ")
+
+	// The methods of several structs would collide on the receiver type
+	config.Getters[0].StructsMatching = "^User"
+	err = Generate(config)
+	assert.ErrorContains(t, err, "getter Get declares its methods on UserView, but structs User and UserAdmin of package "+tempDir+" both match structs_matching \"^User\", only one can (getters[0].output.receiver_type)")
+
+	config.Getters[0].StructsMatching = "^Account$"
+	err = Generate(config)
+	assert.ErrorContains(t, err, "getter Get declares its methods on UserView, but no struct matches structs_matching \"^Account$\" (getters[0].output.receiver_type)")
+
+	// The receiver type must be declared in the package of the struct
+	config.Getters[0].StructsMatching = "^User$"
+	config.Getters[0].Output.ReceiverType = "UserPage"
+	err = Generate(config)
+	assert.ErrorContains(t, err, "getter Get declares its methods on UserPage, but package "+tempDir+" doesn't declare it (getters[0].output.receiver_type)")
+
+	// Getters of different structs can't declare the same method on a receiver
+	config.Getters[0].Output.ReceiverType = "UserView"
+	config.Getters = append(config.Getters, ConfigGetter{
+		Name:            "GetAdmin",
+		Returns:         []string{"json"},
+		StructsMatching: "^UserAdmin$",
+		Output: ConfigGetterOutput{
+			Prefix:       "Get",
+			ReceiverType: "UserView",
+		},
+	})
+	content = strings.Replace(content, "Role string", "Name string", 1)
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+	err = Generate(config)
+	assert.ErrorContains(t, err, "getters of structs User and UserAdmin both declare method GetName on UserView of package "+tempDir+" (getters output.receiver_type)")
+}
+
+func TestGenerate_IndentWithSpaces(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type User struct {
+	Name string ` + "`json:\"name\"`" + `
+	Age  int    ` + "`json:\"age\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config := &Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Output: ConfigOutput{
+			FileName: "constants_gen.go",
+			Indent:   "spaces:4",
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "json",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTagThenField,
+					TagPriority: []string{"json"},
+				},
+			},
+		},
+	}
+
+	err := Generate(config)
+	require.NoError(t, err)
+
+	generated, err := os.ReadFile(filepath.Join(tempDir, "constants_gen.go"))
+	require.NoError(t, err)
+	generatedStr := string(generated)
+
+	// The code is gofmt formatted before the tabs are replaced
+	expectedOutput := `
+const (
+    JsonUserName = "name"
+    JsonUserAge  = "age"
+)`
+	assert.Contains(t, generatedStr, expectedOutput)
+	assert.NotContains(t, generatedStr, "\t")
+}
+
+func TestGenerate_FieldIndex(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type Base struct{}
+
+type User struct {
+	Base
+	ID         string ` + "`constago:\"exclude\"`" + `
+	Name, Nick string
+	_          int
+	secret     string
+	Email      string
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config := &Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+			Struct: ConfigInputStruct{
+				Only: "^User$",
+			},
+		},
+		Output: ConfigOutput{
+			FileName:       "constants_gen.go",
+			EmitFieldIndex: boolPtr(true),
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "json",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTagThenField,
+					TagPriority: []string{"json"},
+				},
+			},
+		},
+	}
+
+	err := Generate(config)
+	require.NoError(t, err)
+
+	generated, err := os.ReadFile(filepath.Join(tempDir, "constants_gen.go"))
+	require.NoError(t, err)
+	generatedStr := string(generated)
+
+	// Indexes count the embedded, excluded, blank and unexported fields, as
+	// reflect does
+	expectedOutput := `
+const (
+	JsonUserName = "Name"
+	UserNameIndex = 2
+	JsonUserNick = "Nick"
+	UserNickIndex = 3
+	JsonUserEmail = "Email"
+	UserEmailIndex = 6
+)`
+	assert.Contains(t, generatedStr, expectedOutput)
+	assert.NotContains(t, generatedStr, "UserIDIndex")
+}
+
+func TestGenerate_GroupByTag(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type User struct {
+	ID    string ` + "`json:\"id\"`" + `
+	Email string ` + "`json:\"email\" group:\"Contact\"`" + `
+	Name  string ` + "`json:\"name\" group:\"Profile\"`" + `
+	Phone string ` + "`json:\"phone\" group:\"Contact\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config := &Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Output: ConfigOutput{
+			FileName:   "constants_gen.go",
+			GroupByTag: "group",
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "json",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTagThenField,
+					TagPriority: []string{"json"},
+				},
+			},
+		},
+	}
+
+	err := Generate(config)
+	require.NoError(t, err)
+
+	generated, err := os.ReadFile(filepath.Join(tempDir, "constants_gen.go"))
+	require.NoError(t, err)
+	generatedStr := string(generated)
+
+	// Ungrouped fields come first, then the groups in order of appearance
+	expectedOutput := `
+// Constants for User
+const (
+	JsonUserId = "id"
+	// Contact
+	JsonUserEmail = "email"
+	JsonUserPhone = "phone"
+	// Profile
+	JsonUserName = "name"
+)`
+	assert.Contains(t, generatedStr, expectedOutput)
+}
+
+func TestGenerate_DocOutputMode(t *testing.T) {
+	tempDir := t.TempDir()
+	notesDir := filepath.Join(tempDir, "notes")
+	require.NoError(t, os.MkdirAll(notesDir, 0755))
+
+	userContent := `package main
+
+type User struct {
+	Name string ` + "`json:\"name\" doc:\"Full name of the user\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "user.go"), []byte(userContent), 0644))
+
+	// Only documented, so the package gets no generated file
+	noteContent := `package notes
+
+type Note struct {
+	Body string ` + "`doc:\"Text of the note\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(filepath.Join(notesDir, "note.go"), []byte(noteContent), 0644))
+
+	config := &Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Output: ConfigOutput{
+			FileName: "constants_gen.go",
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "json",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTag,
+					TagPriority: []string{"json"},
+				},
+			},
+			{
+				Name: "doc",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTag,
+					TagPriority: []string{"doc"},
+				},
+				Output: ConfigTagOutput{
+					Mode: OutputModeDoc,
+				},
+			},
+		},
+	}
+
+	err := Generate(config)
+	require.NoError(t, err)
+
+	generated, err := os.ReadFile(filepath.Join(tempDir, "constants_gen.go"))
+	require.NoError(t, err)
+	generatedStr := string(generated)
+	assert.Contains(t, generatedStr, `JsonUserName = "name"`)
+	assert.NotContains(t, generatedStr, "Full name of the user")
+	assert.NoFileExists(t, filepath.Join(notesDir, "constants_gen.go"))
+
+	// The values are recorded in the model
+	cfg, err := NewConfig(config)
+	require.NoError(t, err)
+	model, err := NewModelBuilder(cfg).Build()
+	require.NoError(t, err)
+
+	dump, err := model.DumpJSON()
+	require.NoError(t, err)
+	assert.Contains(t, string(dump), "Full name of the user")
+	assert.Contains(t, string(dump), "Text of the note")
+
+	// Getters can't return doc elements
+	config.Getters = []ConfigGetter{{Name: "Doc", Returns: []string{"doc"}}}
+	err = Generate(config)
+	assert.ErrorContains(t, err, "getters[0].returns[0]")
+}
+
+// capturingWriter is an OutputWriter keeping the content of each package
+type capturingWriter struct {
+	contents map[string]string
+}
+
+func (w *capturingWriter) WritePackage(pkg *PackageModel, content []byte) error {
+	w.contents[pkg.Path] = string(content)
+	return nil
+}
+
+func TestGenerateTo_CustomWriter(t *testing.T) {
+	tempDir := t.TempDir()
+	modelDir := filepath.Join(tempDir, "model")
+	require.NoError(t, os.MkdirAll(modelDir, 0755))
+
+	userContent := `package main
+
+type User struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "user.go"), []byte(userContent), 0644))
+	orderContent := `package model
+
+type Order struct {
+	ID string ` + "`json:\"id\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(filepath.Join(modelDir, "order.go"), []byte(orderContent), 0644))
+
+	config := &Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Output: ConfigOutput{
+			FileName: "constants_gen.go",
+			Indent:   "spaces:2",
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "json",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTag,
+					TagPriority: []string{"json"},
+				},
+			},
+		},
+	}
+
+	writer := &capturingWriter{contents: map[string]string{}}
+	err := GenerateTo(config, writer)
+	require.NoError(t, err)
+
+	require.Len(t, writer.contents, 2)
+	assert.Contains(t, writer.contents[tempDir], "package main")
+	assert.Contains(t, writer.contents[tempDir], `  JsonUserName = "name"`)
+	assert.Contains(t, writer.contents[modelDir], "package model")
+	assert.Contains(t, writer.contents[modelDir], `  JsonOrderId = "id"`)
+
+	// Nothing is written to the output files
+	assert.NoFileExists(t, filepath.Join(tempDir, "constants_gen.go"))
+	assert.NoFileExists(t, filepath.Join(modelDir, "constants_gen.go"))
+}
+
+// failingWriter is an OutputWriter failing for every package
+type failingWriter struct{}
+
+func (w *failingWriter) WritePackage(pkg *PackageModel, content []byte) error {
+	return errors.New("failed to write " + pkg.Path)
+}
+
+func TestGenerateTo_PackageErrorsOrder(t *testing.T) {
+	tempDir := t.TempDir()
+
+	for _, dir := range []string{"c", "a", "b"} {
+		pkgDir := filepath.Join(tempDir, dir)
+		require.NoError(t, os.MkdirAll(pkgDir, 0755))
+		content := `package ` + dir + `
+
+type User struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+`
+		require.NoError(t, os.WriteFile(filepath.Join(pkgDir, "user.go"), []byte(content), 0644))
+	}
+
+	tests := []struct {
+		name     string
+		parallel bool
+	}{
+		{name: "sequential", parallel: false},
+		{name: "parallel", parallel: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{
+				Input: ConfigInput{
+					Dir: tempDir,
+				},
+				Output: ConfigOutput{
+					Parallel: boolPtr(tt.parallel),
+				},
+				Elements: []ConfigTag{
+					{Name: "json"},
+				},
+			}
+
+			// Every package is attempted, and the errors are sorted by path
+			for i := 0; i < 5; i++ {
+				err := GenerateTo(config, &failingWriter{})
+				assert.EqualError(t, err, strings.Join([]string{
+					"failed to write " + filepath.Join(tempDir, "a"),
+					"failed to write " + filepath.Join(tempDir, "b"),
+					"failed to write " + filepath.Join(tempDir, "c"),
+				}, "\n"))
+			}
+		})
+	}
+}
+
+func TestGenerate_WithExtraImports(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+import "strings"
+
+type User struct {
+	Name strings.Builder ` + "`json:\"name\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config := &Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Output: ConfigOutput{
+			FileName:     "imports_gen.go",
+			ExtraImports: []string{"strings", "_ embed", "tpl \"text/template\""},
+		},
+		Getters: []ConfigGetter{
+			{
+				Name:    "GetValue",
+				Returns: []string{":value"},
+			},
+		},
+	}
+
+	err := Generate(config)
+	require.NoError(t, err)
+
+	generated, err := os.ReadFile(filepath.Join(tempDir, "imports_gen.go"))
+	require.NoError(t, err)
+
+	// Extra imports are deduplicated against the discovered ones
+	expectedOutput := `
+import (
+	_ "embed"
+	strings "strings"
+	tpl "text/template"
+)`
+	assert.Contains(t, string(generated), expectedOutput)
+}
+
+func TestGenerate_NormalizedConstants(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type User struct {
+	Name    string ` + "`json:\"Name\"`" + `
+	Country string ` + "`json:\"countryCode\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config := &Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Output: ConfigOutput{
+			FileName: "normalized_gen.go",
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "json",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTagThenField,
+					TagPriority: []string{"json"},
+				},
+				Output: ConfigTagOutput{
+					Mode:           OutputModeConstant,
+					EmitNormalized: boolPtr(true),
+				},
+			},
+		},
+	}
+
+	err := Generate(config)
+	require.NoError(t, err)
+
+	generated, err := os.ReadFile(filepath.Join(tempDir, "normalized_gen.go"))
+	require.NoError(t, err)
+
+	expectedOutput := `
+// Constants for User
+const (
+	JsonUserName = "Name"
+	JsonUserNameLower = "name"
+	JsonUserCountry = "countryCode"
+	JsonUserCountryLower = "countrycode"
+)`
+	assert.Contains(t, string(generated), expectedOutput)
+}
+
+func TestGenerate_GettersReturningStructElement(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type User struct {
+	Name string   ` + "`json:\"name\"`" + `
+	Tags []string ` + "`json:\"tags\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config := &Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Output: ConfigOutput{
+			FileName: "struct_getters_gen.go",
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "json",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTag,
+					TagPriority: []string{"json"},
+				},
+				Output: ConfigTagOutput{
+					Mode:             OutputModeStruct,
+					CollectionSuffix: "List",
+					Format: ConfigTagOutputFormat{
+						Holder: ConstantFormatCamel,
+					},
+				},
+			},
+		},
+		Getters: []ConfigGetter{
+			{
+				Name:    "Json",
+				Returns: []string{":value", "json"},
+			},
+		},
+	}
+
+	err := Generate(config)
+	require.NoError(t, err)
+
+	generated, err := os.ReadFile(filepath.Join(tempDir, "struct_getters_gen.go"))
+	require.NoError(t, err)
+	generatedStr := string(generated)
+
+	// The holder keeps its own field names while the getters inline the values
+	expectedHolder := `
+var JsonUser = struct {
+	name string
+	tagsList string
+}{
+	name: "name",
+	tagsList: "tags",
+}`
+	assert.Contains(t, generatedStr, expectedHolder)
+
+	expectedGetters := `
+// JsonName returns the configured values for User
+func (_struct *User) JsonName() (string, string) {
+	return  _struct.Name, "name"
+}
+// JsonTags returns the configured values for User
+func (_struct *User) JsonTags() ([]string, string) {
+	return  _struct.Tags, "tags"
+}`
+	assert.Contains(t, generatedStr, expectedGetters)
+
+	_, err = parser.ParseFile(token.NewFileSet(), "", generated, 0)
+	assert.NoError(t, err)
+
+	// A struct turning the element into doc mode has nothing to return
+	orderContent := `package main
+
+//constago:mode=doc
+type Order struct {
+	ID string ` + "`json:\"id\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "order.go"), []byte(orderContent), 0644))
+	err = Generate(config)
+	assert.ErrorContains(t, err, "order.go:5: getter Json can't return element json of field Order.ID in doc mode, only constant, struct and none modes can be returned")
+}
+
+func TestGenerate_EmptyStructConstant(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "events.go")
+	content := `package main
+
+type Event struct{}
+
+type User struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config := &Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Output: ConfigOutput{
+			FileName:           "empty_gen.go",
+			EmitStructConstant: boolPtr(true),
+			EmitEmptyStructs:   boolPtr(true),
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "json",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTag,
+					TagPriority: []string{"json"},
+				},
+			},
+		},
+	}
+
+	err := Generate(config)
+	require.NoError(t, err)
+
+	generated, err := os.ReadFile(filepath.Join(tempDir, "empty_gen.go"))
+	require.NoError(t, err)
+	generatedStr := string(generated)
+
+	expectedEvent := `
+// Constants for Event
+const (
+	EventStructName = "Event"
+)`
+	assert.Contains(t, generatedStr, expectedEvent)
+
+	expectedUser := `
+// Constants for User
+const (
+	UserStructName = "User"
+	JsonUserName = "name"
+)`
+	assert.Contains(t, generatedStr, expectedUser)
+}
+
+func TestGenerate_EmitBothConstants(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type User struct {
+	Name  string ` + "`json:\"name\"`" + `
+	Email string ` + "`json:\"email_address\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config := &Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Output: ConfigOutput{
+			FileName: "both_gen.go",
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "json",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTag,
+					TagPriority: []string{"json"},
+				},
+				Output: ConfigTagOutput{
+					Mode:     OutputModeConstant,
+					EmitBoth: boolPtr(true),
+				},
+			},
+		},
+	}
+
+	err := Generate(config)
+	require.NoError(t, err)
+
+	generated, err := os.ReadFile(filepath.Join(tempDir, "both_gen.go"))
+	require.NoError(t, err)
+
+	expectedOutput := `
+// Constants for User
+const (
+	JsonUserName = "name"
+	JsonUserNameField = "Name"
+	JsonUserEmail = "email_address"
+	JsonUserEmailField = "Email"
+)`
+	assert.Contains(t, string(generated), expectedOutput)
+
+	// With typed keys, only the element values are typed and listed
+	config.Elements[0].Output.TypedKey = boolPtr(true)
+	config.Elements[0].Output.EmitValues = boolPtr(true)
+	config.Elements[0].Output.EmitNormalized = boolPtr(true)
+	config.Elements[0].Output.Normalization = TransformCaseUpper
+	err = Generate(config)
+	require.NoError(t, err)
+
+	generated, err = os.ReadFile(filepath.Join(tempDir, "both_gen.go"))
+	require.NoError(t, err)
+
+	expectedOutput = `
+const (
+	JsonUserKeyName JsonUserKey = "name"
+	JsonUserKeyNameUpper = "NAME"
+	JsonUserKeyNameField = "Name"
+	JsonUserKeyEmail JsonUserKey = "email_address"
+	JsonUserKeyEmailUpper = "EMAIL_ADDRESS"
+	JsonUserKeyEmailField = "Email"
+)`
+	assert.Contains(t, string(generated), expectedOutput)
+	assert.Contains(t, string(generated), `
+	return []JsonUserKey{
+		JsonUserKeyName,
+		JsonUserKeyEmail,
+	}`)
+}
+
+func TestGenerate_GettersWithAliasedImport(t *testing.T) {
+	tempDir := t.TempDir()
+
+	goMod := "module github.com/example\n\ngo 1.22\n"
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "go.mod"), []byte(goMod), 0644))
+
+	booleansDir := filepath.Join(tempDir, "booleans")
+	require.NoError(t, os.MkdirAll(booleansDir, 0755))
+	booleansSrc := "package booleans\n\ntype Boolean struct{}\n"
+	require.NoError(t, os.WriteFile(filepath.Join(booleansDir, "booleans.go"), []byte(booleansSrc), 0644))
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+import binary "github.com/example/booleans"
+
+type User struct {
+	Enabled binary.Boolean ` + "`json:\"enabled\"`" + `
+}
+
+func main() {}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config := &Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Output: ConfigOutput{
+			FileName: "aliased_gen.go",
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "json",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTag,
+					TagPriority: []string{"json"},
+				},
+				Output: ConfigTagOutput{
+					Mode: OutputModeNone,
+				},
+			},
+		},
+		Getters: []ConfigGetter{
+			{
+				Name:    "Get",
+				Returns: []string{":value", "json"},
+			},
+		},
+	}
+
+	err := Generate(config)
+	require.NoError(t, err)
+
+	generated, err := os.ReadFile(filepath.Join(tempDir, "aliased_gen.go"))
+	require.NoError(t, err)
+	generatedStr := string(generated)
+
+	assert.Contains(t, generatedStr, "\tbinary \"github.com/example/booleans\"\n")
+	assert.Contains(t, generatedStr, "func (_struct *User) GetEnabled() (binary.Boolean, string) {")
+
+	// The generated getter must compile along with the source
+	cmd := exec.Command("go", "build", "./...")
+	cmd.Dir = tempDir
+	out, err := cmd.CombinedOutput()
+	assert.NoError(t, err, string(out))
+}
+
+func TestGenerate_GettersWithCollidingSourceAlias(t *testing.T) {
+	tempDir := t.TempDir()
+
+	goMod := "module github.com/example\n\ngo 1.22\n"
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "go.mod"), []byte(goMod), 0644))
+
+	// The binary alias of a.go names another package in b.go
+	aContent := `package main
+
+import binary "encoding/json"
+
+type A struct {
+	X binary.Number ` + "`json:\"x\"`" + `
+}
+
+func main() {}
+`
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "a.go"), []byte(aContent), 0644))
+	bContent := `package main
+
+import "encoding/binary"
+
+type B struct {
+	Y binary.ByteOrder ` + "`json:\"y\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "b.go"), []byte(bContent), 0644))
+
+	config := &Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Output: ConfigOutput{
+			FileName: "values_gen.go",
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "json",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTag,
+					TagPriority: []string{"json"},
+				},
+			},
+		},
+		Getters: []ConfigGetter{
+			{
+				Name:    "Value",
+				Returns: []string{":value"},
+			},
+		},
+	}
+
+	require.NoError(t, Generate(config))
+
+	generated, err := os.ReadFile(filepath.Join(tempDir, "values_gen.go"))
+	require.NoError(t, err)
+	generatedStr := string(generated)
+
+	// Imports are aliased by path, so the alias of encoding/json is renamed
+	// and the getter follows it
+	assert.Contains(t, generatedStr, "\tbinary \"encoding/binary\"\n")
+	assert.Contains(t, generatedStr, "\t_binary \"encoding/json\"\n")
+	assert.Contains(t, generatedStr, "func (_struct *A) ValueX() (_binary.Number) {")
+	assert.Contains(t, generatedStr, "func (_struct *B) ValueY() (binary.ByteOrder) {")
+
+	cmd := exec.Command("go", "vet", "./...")
+	cmd.Dir = tempDir
+	out, err := cmd.CombinedOutput()
+	assert.NoError(t, err, string(out))
+}
+
+func TestGenerate_MergeMarkers(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type User struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config := &Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Output: ConfigOutput{
+			FileName:     "keys.go",
+			MergeMarkers: boolPtr(true),
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "json",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTag,
+					TagPriority: []string{"json"},
+				},
+			},
+		},
+	}
+
+	t.Run("replaces only the marked region", func(t *testing.T) {
+		handWritten := `package main
+
+// Keys maintained by hand
+const ExtraKey = "extra"
+
+// constago:begin
+const Stale = "stale"
+// constago:end
+
+func keys() []string { return []string{ExtraKey} }
+`
+		outputFile := filepath.Join(tempDir, "keys.go")
+		require.NoError(t, os.WriteFile(outputFile, []byte(handWritten), 0644))
+
+		require.NoError(t, Generate(config))
+		// Merging again is idempotent
+		require.NoError(t, Generate(config))
+
+		generated, err := os.ReadFile(outputFile)
+		require.NoError(t, err)
+
+		expected := `package main
+
+// Keys maintained by hand
+const ExtraKey = "extra"
+
+// constago:begin
+// Constants for User
+const (
+	JsonUserName = "name"
+)
+
+// constago:end
+
+func keys() []string { return []string{ExtraKey} }
+`
+		assert.Equal(t, expected, string(generated))
+	})
+
+	t.Run("creates a missing file with markers", func(t *testing.T) {
+		outputFile := filepath.Join(tempDir, "keys.go")
+		require.NoError(t, os.Remove(outputFile))
+
+		require.NoError(t, Generate(config))
+
+		generated, err := os.ReadFile(outputFile)
+		require.NoError(t, err)
+		generatedStr := string(generated)
+
+		assert.True(t, strings.HasPrefix(generatedStr, "package main\n"))
+		assert.Contains(t, generatedStr, "// constago:begin\n// Constants for User\n")
+		assert.True(t, strings.HasSuffix(generatedStr, "\n// constago:end"))
+	})
+
+	t.Run("fails without markers", func(t *testing.T) {
+		outputFile := filepath.Join(tempDir, "keys.go")
+		require.NoError(t, os.WriteFile(outputFile, []byte("package main\n"), 0644))
+
+		err := Generate(config)
+		assert.ErrorContains(t, err, "must contain the \"// constago:begin\" and \"// constago:end\" markers")
+	})
+}
+
+func TestGenerate_MergeMarkersImports(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+import "time"
+
+type User struct {
+	Name      string    ` + "`json:\"name\"`" + `
+	CreatedAt time.Time ` + "`json:\"created_at\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	// The host file imports strings but not the time package of the getters
+	handWritten := `package main
+
+import "strings"
+
+func upperKey(key string) string { return strings.ToUpper(key) }
+
+// constago:begin
+// constago:end
+`
+	outputFile := filepath.Join(tempDir, "keys.go")
+	require.NoError(t, os.WriteFile(outputFile, []byte(handWritten), 0644))
+
+	config := &Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Output: ConfigOutput{
+			FileName:     "keys.go",
+			MergeMarkers: boolPtr(true),
+		},
+		Elements: []ConfigTag{
+			{Name: "json"},
+		},
+		Getters: []ConfigGetter{
+			{
+				Name:    "Json",
+				Returns: []string{":value", "json"},
+			},
+		},
+	}
+
+	require.NoError(t, Generate(config))
+
+	generated, err := os.ReadFile(outputFile)
+	require.NoError(t, err)
+
+	expectedImports := `import (
+	"strings"
+	"time"
+)`
+	assert.Contains(t, string(generated), expectedImports)
+	assert.Contains(t, string(generated), "func (_struct *User) JsonCreatedAt() (time.Time, string) {")
+
+	// The merged file is formatted
+	formatted, err := format.Source(generated)
+	require.NoError(t, err)
+	assert.Equal(t, string(formatted), string(generated))
+}
+
+func TestGenerate_SkipsUnchangedOutput(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type User struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	newConfig := func(force bool) *Config {
+		return &Config{
+			Input: ConfigInput{
+				Dir: tempDir,
+			},
+			Output: ConfigOutput{
+				FileName: "unchanged_gen.go",
+				Force:    boolPtr(force),
+			},
+			Elements: []ConfigTag{
+				{
+					Name: "json",
+					Input: ConfigTagInput{
+						Mode:        InputModeTypeTag,
+						TagPriority: []string{"json"},
+					},
+				},
+			},
+		}
+	}
+
+	outputFile := filepath.Join(tempDir, "unchanged_gen.go")
+	require.NoError(t, Generate(newConfig(false)))
+
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	require.NoError(t, os.Chtimes(outputFile, past, past))
+
+	// Same content, the file isn't touched
+	require.NoError(t, Generate(newConfig(false)))
+	info, err := os.Stat(outputFile)
+	require.NoError(t, err)
+	assert.True(t, info.ModTime().Equal(past))
+
+	// Forced, the file is rewritten
+	require.NoError(t, Generate(newConfig(true)))
+	info, err = os.Stat(outputFile)
+	require.NoError(t, err)
+	assert.True(t, info.ModTime().After(past))
+}
+
+func TestGenerate_StructFieldTagsCopy(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type User struct {
+	Name  string ` + "`json:\"name\" db:\"user_name\"`" + `
+	Email string "json:\"email\""
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config := &Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Output: ConfigOutput{
+			FileName: "tags_gen.go",
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "json",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTag,
+					TagPriority: []string{"json"},
+				},
+				Output: ConfigTagOutput{
+					Mode:            OutputModeStruct,
+					StructFieldTags: StructFieldTagsCopy,
+				},
+			},
+		},
+	}
+
+	err := Generate(config)
+	require.NoError(t, err)
+
+	generated, err := os.ReadFile(filepath.Join(tempDir, "tags_gen.go"))
+	require.NoError(t, err)
+
+	expectedOutput := "\nvar JsonUser = struct {\n" +
+		"\tName string `json:\"name\" db:\"user_name\"`\n" +
+		"\tEmail string `json:\"email\"`\n" +
+		"}{\n" +
+		"\tName: \"name\",\n" +
+		"\tEmail: \"email\",\n" +
+		"}"
+	assert.Contains(t, string(generated), expectedOutput)
+
+	_, err = parser.ParseFile(token.NewFileSet(), "", generated, 0)
+	assert.NoError(t, err)
+}
+
+func TestGenerate_PathTemplate(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "model", "user.go")
+	require.NoError(t, os.MkdirAll(filepath.Dir(testFile), 0755))
+	content := `package model
+
+type User struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config := &Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Output: ConfigOutput{
+			FileName:     "keys_gen.go",
+			PathTemplate: "{{ .Package.Path }}/internal/keys/{{ .FileName }}",
+			PackageName:  "keys",
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "json",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTag,
+					TagPriority: []string{"json"},
+				},
+			},
+		},
+	}
+
+	err := Generate(config)
+	require.NoError(t, err)
+
+	assert.NoFileExists(t, filepath.Join(tempDir, "model", "keys_gen.go"))
+
+	generated, err := os.ReadFile(filepath.Join(tempDir, "model", "internal", "keys", "keys_gen.go"))
+	require.NoError(t, err)
+	generatedStr := string(generated)
+
+	assert.Contains(t, generatedStr, "package keys\n")
+	assert.Contains(t, generatedStr, "JsonUserName = \"name\"")
+
+	// The generated file isn't scanned again on the next run
+	require.NoError(t, Generate(config))
+}
+
+func TestGenerate_InternalImports(t *testing.T) {
+	tempDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "go.mod"), []byte("module example.com/app\n\ngo 1.22\n"), 0644))
+
+	typesDir := filepath.Join(tempDir, "service", "internal", "types")
+	require.NoError(t, os.MkdirAll(typesDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(typesDir, "types.go"), []byte("package types\n\ntype Kind string\n"), 0644))
+
+	content := `package service
+
+import "example.com/app/service/internal/types"
+
+type Job struct {
+	Kind types.Kind ` + "`json:\"kind\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "service", "job.go"), []byte(content), 0644))
+
+	buildConfig := func(pathTemplate string) *Config {
+		return &Config{
+			Input: ConfigInput{
+				Dir:          filepath.Join(tempDir, "service"),
+				ResolveTypes: boolPtr(false),
+			},
+			Output: ConfigOutput{
+				FileName:     "job_gen.go",
+				PathTemplate: pathTemplate,
+			},
+			Elements: []ConfigTag{
+				{
+					Name: "json",
+					Input: ConfigTagInput{
+						Mode:        InputModeTypeTag,
+						TagPriority: []string{"json"},
+					},
+				},
+			},
+			Getters: []ConfigGetter{
+				{
+					Name:    "Value",
+					Returns: []string{":value"},
+				},
+			},
+		}
+	}
+
+	t.Run("allowed within the parent of internal", func(t *testing.T) {
+		err := Generate(buildConfig("{{ .Package.Path }}/jobs/{{ .FileName }}"))
+		require.NoError(t, err)
+		assert.FileExists(t, filepath.Join(tempDir, "service", "jobs", "job_gen.go"))
+	})
+
+	t.Run("error outside the parent of internal", func(t *testing.T) {
+		err := Generate(buildConfig("{{ .Package.Path }}/../gen/{{ .FileName }}"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "can't import the internal package example.com/app/service/internal/types")
+		assert.Contains(t, err.Error(), "only allowed within example.com/app/service")
+		assert.NoFileExists(t, filepath.Join(tempDir, "gen", "job_gen.go"))
+	})
+}
+
+func TestGenerate_SplitVisibility(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type User struct {
+	Name   string ` + "`json:\"name\" xml:\"name\"`" + `
+	secret string ` + "`json:\"secret\" xml:\"secret\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config := &Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+			Field: ConfigInputField{
+				IncludeUnexported: boolPtr(true),
+			},
+		},
+		Output: ConfigOutput{
+			FileName:        "split_gen.go",
+			SplitVisibility: boolPtr(true),
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "json",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTag,
+					TagPriority: []string{"json"},
+				},
+				Output: ConfigTagOutput{
+					Mode: OutputModeConstant,
+				},
+			},
+			{
+				Name: "xml",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTag,
+					TagPriority: []string{"xml"},
+				},
+				Output: ConfigTagOutput{
+					Mode: OutputModeStruct,
+				},
+			},
+		},
+	}
+
+	err := Generate(config)
+	require.NoError(t, err)
+
+	generated, err := os.ReadFile(filepath.Join(tempDir, "split_gen.go"))
+	require.NoError(t, err)
+	generatedStr := string(generated)
+
+	expectedConstants := `
+// Constants for User
+const (
+	JsonUserName = "name"
+)
+// Unexported constants for User
+const (
+	jsonUserSecret = "secret"
+)`
+	assert.Contains(t, generatedStr, expectedConstants)
+
+	expectedExported := `
+// XmlUser contains field constants for User
+var XmlUser = struct {
+	Name string
+}{
+	Name: "name",
+}`
+	assert.Contains(t, generatedStr, expectedExported)
+
+	expectedUnexported := `
+// xmlUser contains field constants for User
+var xmlUser = struct {
+	Secret string
+}{
+	Secret: "secret",
+}`
+	assert.Contains(t, generatedStr, expectedUnexported)
+}
+
+func TestGenerate_SplitExported(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type User struct {
+	Name   string ` + "`json:\"name\" xml:\"name\"`" + `
+	secret string ` + "`json:\"secret\" xml:\"secret\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config := &Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+			Field: ConfigInputField{
+				IncludeUnexported: boolPtr(true),
+			},
+		},
+		Output: ConfigOutput{
+			FileName:        "constago_gen.go",
+			SplitVisibility: boolPtr(true),
+			SplitExported:   boolPtr(true),
+			ExtraImports:    []string{"_ embed"},
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "json",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTag,
+					TagPriority: []string{"json"},
+				},
+			},
+			{
+				Name: "xml",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTag,
+					TagPriority: []string{"xml"},
+				},
+				Output: ConfigTagOutput{
+					Mode: OutputModeStruct,
+				},
+			},
+		},
+	}
+
+	err := Generate(config)
+	require.NoError(t, err)
+
+	generated, err := os.ReadFile(filepath.Join(tempDir, "constago_gen.go"))
+	require.NoError(t, err)
+	exportedStr := string(generated)
+
+	generated, err = os.ReadFile(filepath.Join(tempDir, "constago_internal_gen.go"))
+	require.NoError(t, err)
+	internalStr := string(generated)
+
+	assert.Contains(t, exportedStr, "package main\n")
+	assert.Contains(t, exportedStr, "\tJsonUserName = \"name\"\n")
+	assert.Contains(t, exportedStr, "var XmlUser = struct {")
+	assert.NotContains(t, exportedStr, "jsonUserSecret")
+	assert.NotContains(t, exportedStr, "xmlUser")
+
+	assert.Contains(t, internalStr, "package main\n")
+	assert.Contains(t, internalStr, "\tjsonUserSecret = \"secret\"\n")
+	assert.Contains(t, internalStr, "var xmlUser = struct {")
+	assert.NotContains(t, internalStr, "JsonUserName")
+	assert.NotContains(t, internalStr, "XmlUser")
+
+	// Extra imports go with the first file only
+	assert.Contains(t, exportedStr, "_ \"embed\"")
+	assert.NotContains(t, internalStr, "embed")
+
+	// Neither file is scanned again on the next run
+	require.NoError(t, Generate(config))
+
+	// Other writers tell the parts apart by their file name
+	writer := &fileNameWriter{contents: map[string]string{}}
+	require.NoError(t, GenerateTo(config, writer))
+	require.Len(t, writer.contents, 2)
+	assert.Contains(t, writer.contents["constago_gen.go"], "JsonUserName")
+	assert.Contains(t, writer.contents["constago_internal_gen.go"], "jsonUserSecret")
+
+	// Both parts can't go to the same file
+	config.Output.PathTemplate = "{{ .Package.Path }}/all_gen.go"
+	err = Generate(config)
+	assert.ErrorContains(t, err, "output.path_template writes the exported and the unexported declarations of "+tempDir+" to the same file "+filepath.Join(tempDir, "all_gen.go"))
+	assert.NoFileExists(t, filepath.Join(tempDir, "all_gen.go"))
+}
+
+// fileNameWriter is an OutputWriter keeping the content of each file name
+type fileNameWriter struct {
+	contents map[string]string
+}
+
+func (w *fileNameWriter) WritePackage(pkg *PackageModel, content []byte) error {
+	w.contents[pkg.FileName] = string(content)
+	return nil
+}
+
+func TestGenerate_PackageDoc(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package model
+
+type User struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config := &Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Output: ConfigOutput{
+			FileName:   "doc_gen.go",
+			PackageDoc: "Package {{ .PackageName }} holds the generated keys.\n\nDo not edit.  ",
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "json",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTag,
+					TagPriority: []string{"json"},
+				},
+			},
+		},
+	}
+
+	err := Generate(config)
+	require.NoError(t, err)
+
+	generated, err := os.ReadFile(filepath.Join(tempDir, "doc_gen.go"))
+	require.NoError(t, err)
+
+	expectedHeader := `// Code generated by constago generator; DO NOT EDIT.
+// This file was produced from the scanning model and configuration.
+
+// Package model holds the generated keys.
+//
+// Do not edit.
+package model
+`
+	assert.True(t, strings.HasPrefix(string(generated), expectedHeader), string(generated))
+
+	// The doc comment is attached to the package clause
+	file, err := parser.ParseFile(token.NewFileSet(), "doc_gen.go", generated, parser.ParseComments)
+	require.NoError(t, err)
+	require.NotNil(t, file.Doc)
+	assert.Equal(t, "Package model holds the generated keys.\n\nDo not edit.\n", file.Doc.Text())
+}
+
+func TestGenerate_Templates(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type User struct {
+	Name  string ` + "`json:\"name\"`" + `
+	Email string ` + "`json:\"email\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	templatesDir := t.TempDir()
+	base := `// Custom output
+package {{ .PackageName }}
+{{ range $struct := .Package.Structs }}{{ template "struct" $struct }}{{ end }}`
+	partial := `{{ define "struct" }}
+// {{ upper .Name }} keys
+var {{ camel .Name }}Keys = []string{ {{- range $i, $c := .Constants }}{{ if $i }}, {{ end }}"{{ $c.Value }}"{{ end -}} }
+{{ end }}`
+	basePath := filepath.Join(templatesDir, "base.tpl")
+	partialPath := filepath.Join(templatesDir, "partial.tpl")
+	require.NoError(t, os.WriteFile(basePath, []byte(base), 0644))
+	require.NoError(t, os.WriteFile(partialPath, []byte(partial), 0644))
+
+	config := &Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Output: ConfigOutput{
+			FileName:  "custom_gen.go",
+			Templates: []string{basePath, partialPath},
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "json",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTag,
+					TagPriority: []string{"json"},
+				},
+			},
+		},
+	}
+
+	err := Generate(config)
+	require.NoError(t, err)
+
+	generated, err := os.ReadFile(filepath.Join(tempDir, "custom_gen.go"))
+	require.NoError(t, err)
+
+	expectedOutput := `// Custom output
+package main
+
+// USER keys
+var userKeys = []string{"name", "email"}
+`
+	assert.Equal(t, expectedOutput, string(generated))
+
+	// The entry must be one of the parsed templates
+	config.Output.TemplateEntry = "missing.tpl"
+	err = Generate(config)
+	assert.EqualError(t, err, "output template entry \"missing.tpl\" is not defined by the output templates")
+}
+
+func TestGenerate_EmitSourceHash(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	writeSource := func(tag string) {
+		content := `package main
+
+type User struct {
+	Name string ` + "`json:\"" + tag + "\"`" + `
+}
+`
+		require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+	}
+
+	config := &Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Output: ConfigOutput{
+			FileName:       "hash_gen.go",
+			EmitSourceHash: boolPtr(true),
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "json",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTag,
+					TagPriority: []string{"json"},
+				},
+			},
+		},
+	}
+
+	hashPattern := regexp.MustCompile(`\n// source-hash: ([0-9a-f]{64})\n// Constants for User\n`)
+	generateHash := func() string {
+		require.NoError(t, Generate(config))
+		generated, err := os.ReadFile(filepath.Join(tempDir, "hash_gen.go"))
+		require.NoError(t, err)
+		match := hashPattern.FindStringSubmatch(string(generated))
+		require.NotNil(t, match, string(generated))
+		return match[1]
+	}
+
+	writeSource("name")
+	first := generateHash()
+	assert.Equal(t, first, generateHash())
+
+	writeSource("full_name")
+	assert.NotEqual(t, first, generateHash())
+}
+
+func TestGenerate_Goimports(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+import "strings"
+
+type User struct {
+	Name strings.Builder ` + "`json:\"name\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config := &Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Output: ConfigOutput{
+			FileName:     "goimports_gen.go",
+			ExtraImports: []string{"fmt", "_ embed", "tpl \"text/template\""},
+			Goimports:    boolPtr(true),
+		},
+		Getters: []ConfigGetter{
+			{
+				Name:    "GetValue",
+				Returns: []string{":value"},
+			},
+		},
+	}
+
+	err := Generate(config)
+	require.NoError(t, err)
+
+	generated, err := os.ReadFile(filepath.Join(tempDir, "goimports_gen.go"))
+	require.NoError(t, err)
+	generatedStr := string(generated)
+
+	// Unused extra imports are pruned, while blank and used imports are kept
+	expectedOutput := `
+import (
+	_ "embed"
+	strings "strings"
+)`
+	assert.Contains(t, generatedStr, expectedOutput)
+	assert.NotContains(t, generatedStr, `"fmt"`)
+	assert.NotContains(t, generatedStr, `"text/template"`)
+}
+
+func TestGenerate_StructsFromSeveralFilesInOneOutput(t *testing.T) {
+	tempDir := t.TempDir()
+
+	files := map[string]string{
+		"user.go": `package main
+
+type User struct {
+	Name string ` + "`json:\"name\" xml:\"name\"`" + `
+}
+`,
+		"company.go": `package main
+
+type Company struct {
+	Title string ` + "`json:\"title\" xml:\"title\"`" + `
+}
+`,
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644))
+	}
+
+	config := &Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Output: ConfigOutput{
+			FileName: "merged_gen.go",
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "json",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTag,
+					TagPriority: []string{"json"},
+				},
+			},
+			{
+				Name: "xml",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTag,
+					TagPriority: []string{"xml"},
+				},
+				Output: ConfigTagOutput{
+					Mode: OutputModeStruct,
+				},
+			},
+		},
+		Getters: []ConfigGetter{
+			{
+				Name:    "Json",
+				Returns: []string{"json"},
+			},
+		},
+	}
+
+	err := Generate(config)
+	require.NoError(t, err)
+
+	generated, err := os.ReadFile(filepath.Join(tempDir, "merged_gen.go"))
+	require.NoError(t, err)
+	generatedStr := string(generated)
+
+	// Both structs of the package are rendered in its single output file
+	for _, expected := range []string{
+		"\tJsonCompanyTitle = \"title\"\n",
+		"var XmlCompany = struct {",
+		"func (_struct *Company) JsonTitle() (string) {",
+		"\tJsonUserName = \"name\"\n",
+		"var XmlUser = struct {",
+		"func (_struct *User) JsonName() (string) {",
+	} {
+		assert.Contains(t, generatedStr, expected)
+	}
+	assert.Equal(t, 1, strings.Count(generatedStr, "package main"))
+
+	entries, err := os.ReadDir(tempDir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 3)
+}
+
+func TestGenerate_PackageWithStructlessFile(t *testing.T) {
+	tempDir := t.TempDir()
+	storeDir := filepath.Join(tempDir, "store")
+	require.NoError(t, os.MkdirAll(storeDir, 0755))
+
+	files := map[string]string{
+		"item.go": `package store
+
+type Item struct {
+	SKU string ` + "`json:\"sku\"`" + `
+}
+`,
+		"helpers.go": `package store
+
+func normalize(s string) string {
+	return s
+}
+`,
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(storeDir, name), []byte(content), 0644))
+	}
+
+	config := &Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Output: ConfigOutput{
+			FileName: "store_gen.go",
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "json",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTag,
+					TagPriority: []string{"json"},
+				},
+			},
+		},
+	}
+
+	err := Generate(config)
+	require.NoError(t, err)
+
+	// The output lands next to the struct file, with its package clause
+	generated, err := os.ReadFile(filepath.Join(storeDir, "store_gen.go"))
+	require.NoError(t, err)
+	generatedStr := string(generated)
+	assert.Contains(t, generatedStr, "package store\n")
+	assert.Contains(t, generatedStr, "\tJsonItemSku = \"sku\"\n")
+
+	entries, err := os.ReadDir(storeDir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 3)
+	assert.NoFileExists(t, filepath.Join(tempDir, "store_gen.go"))
+}
+
+func TestGenerate_MarkdownFormat(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package model
+
+type User struct {
+	Name  string ` + "`json:\"name\" title:\"Full name\"`" + `
+	Email string ` + "`json:\"email\" title:\"E-mail | contact\"`" + `
+	Age   int    ` + "`json:\"age\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config := &Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Output: ConfigOutput{
+			Format: OutputFormatMarkdown,
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "json",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTag,
+					TagPriority: []string{"json"},
+				},
+			},
+			{
+				Name: "title",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTag,
+					TagPriority: []string{"title"},
+				},
+			},
+		},
+	}
+
+	err := Generate(config)
+	require.NoError(t, err)
+
+	generated, err := os.ReadFile(filepath.Join(tempDir, "constago.gen.md"))
+	require.NoError(t, err)
+
+	expectedOutput := `<!-- Code generated by constago generator; DO NOT EDIT. -->
+
+# Package model
+
+## User
+
+| Field | json | title |
+| --- | --- | --- |
+| Name | name | Full name |
+| Email | email | E-mail \| contact |
+| Age | age |  |
+`
+	assert.Equal(t, expectedOutput, string(generated))
+	assert.NoFileExists(t, filepath.Join(tempDir, "constago.gen.go"))
 }
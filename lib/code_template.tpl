@@ -1,7 +1,9 @@
+{{ if not .MergeMarkers -}}
 // Code generated by constago generator; DO NOT EDIT.
 // This file was produced from the scanning model and configuration.
 
-package {{ .Package.Name }}
+{{ end -}}
+{{ .PackageDoc }}package {{ .PackageName }}
 
 import (
 {{- range $alias, $import := .Package.Imports }}
@@ -10,16 +12,79 @@ import (
 {{- end }}
 {{- end }}
 )
+{{- if .MergeMarkers }}
 
+// constago:begin
+{{- end }}
+{{- template "declarations" . }}
+{{- if .MergeMarkers }}
+// constago:end
+{{- end }}
+
+{{- define "declarations" }}
 {{- range $struct := .Package.Structs }}
+{{- if $struct.Source }}
+// {{ $struct.Name }} is declared in {{ $struct.Source }}
+{{- end }}
+{{- if $struct.SourceHash }}
+// source-hash: {{ $struct.SourceHash }}
+{{- end }}
+{{- range $keyType := $struct.KeyTypes }}
+// {{ $keyType.Name }} is the key type for {{ $struct.Name }} fields
+type {{ $keyType.Name }} string
+{{- end }}
 {{- if $struct.Constants }}
 // Constants for {{ $struct.Name }}
-const (
+{{- if eq $.Config.Output.ConstBlock "single" }}
 {{- range $constant := $struct.Constants }}
-	{{ $constant.Name }} = "{{ $constant.Value }}"
+{{- if $constant.GroupStart }}
+// {{ $constant.Group }}
+{{- end }}
+{{ $.Config.Output.Declaration }} {{ $constant.Name }}{{ if $constant.Type }} {{ $constant.Type }}{{ end }} = {{ if $constant.Literal }}{{ $constant.Value }}{{ else }}"{{ $constant.Value }}"{{ end }}{{ if $constant.Comment }} // {{ $constant.Comment }}{{ end }}
+{{- end }}
+{{- else }}
+{{ $.Config.Output.Declaration }} (
+{{- range $constant := $struct.Constants }}
+{{- if $constant.GroupStart }}
+	// {{ $constant.Group }}
+{{- end }}
+	{{ $constant.Name }}{{ if $constant.Type }} {{ $constant.Type }}{{ end }} = {{ if $constant.Literal }}{{ $constant.Value }}{{ else }}"{{ $constant.Value }}"{{ end }}{{ if $constant.Comment }} // {{ $constant.Comment }}{{ end }}
 {{- end }}
 )
+{{- end }}
 
+{{- end }}
+{{- if $struct.UnexportedConstants }}
+// Unexported constants for {{ $struct.Name }}
+{{- if eq $.Config.Output.ConstBlock "single" }}
+{{- range $constant := $struct.UnexportedConstants }}
+{{- if $constant.GroupStart }}
+// {{ $constant.Group }}
+{{- end }}
+{{ $.Config.Output.Declaration }} {{ $constant.Name }}{{ if $constant.Type }} {{ $constant.Type }}{{ end }} = {{ if $constant.Literal }}{{ $constant.Value }}{{ else }}"{{ $constant.Value }}"{{ end }}{{ if $constant.Comment }} // {{ $constant.Comment }}{{ end }}
+{{- end }}
+{{- else }}
+{{ $.Config.Output.Declaration }} (
+{{- range $constant := $struct.UnexportedConstants }}
+{{- if $constant.GroupStart }}
+	// {{ $constant.Group }}
+{{- end }}
+	{{ $constant.Name }}{{ if $constant.Type }} {{ $constant.Type }}{{ end }} = {{ if $constant.Literal }}{{ $constant.Value }}{{ else }}"{{ $constant.Value }}"{{ end }}{{ if $constant.Comment }} // {{ $constant.Comment }}{{ end }}
+{{- end }}
+)
+{{- end }}
+{{- end }}
+{{- range $keyType := $struct.KeyTypes }}
+{{- if $keyType.Values }}
+// Values returns all the {{ $keyType.Name }} values
+func ({{ $keyType.Name }}) Values() []{{ $keyType.Name }} {
+	return []{{ $keyType.Name }}{
+{{- range $value := $keyType.Values }}
+		{{ $value }},
+{{- end }}
+	}
+}
+{{- end }}
 {{- end }}
 
 {{- if $struct.Structs }}
@@ -27,7 +92,7 @@ const (
 // {{ $structOutput.Name }} contains field constants for {{ $struct.Name }}
 var {{ $structOutput.Name }} = struct {
 {{- range $field := $structOutput.Fields }}
-	{{ $field.Name }} string
+	{{ $field.Name }} string{{ if $field.Tag }} `{{ $field.Tag }}`{{ end }}
 {{- end }}
 }{
 {{- range $field := $structOutput.Fields }}
@@ -41,10 +106,19 @@ var {{ $structOutput.Name }} = struct {
 {{- if $struct.Getters }}
 {{- range $getter := $struct.Getters }}
 // {{ $getter.Name }} returns the configured values for {{ $struct.Name }}
-func (_struct *{{ $struct.Name }}) {{ $getter.Name }}() ({{- range $i, $return := $getter.Returns }}{{ if $i }}, {{ end }}{{ if $return.Constant }}string{{ else if $return.Field }}string{{ else if $return.None }}string{{ else if $return.Value }}{{ $return.Value.TypeName }}{{ end }}{{- end }}) {
-	return {{ range $i, $return := $getter.Returns }}{{ if $i }}, {{ end }}{{ if $return.Constant }}"{{ $return.Constant.Value }}"{{ else if $return.Field }}"{{ $return.Field.Value }}"{{ else if $return.None }}"{{ $return.None.Value }}"{{ else if $return.Value }} _struct.{{ $return.Value.FieldName }}{{ end }}{{ end }}
+func (_struct *{{ if $getter.Receiver }}{{ $getter.Receiver }}{{ else }}{{ $struct.Name }}{{ end }}) {{ $getter.Name }}() ({{- range $i, $return := $getter.Returns }}{{ if $i }}, {{ end }}{{ if $return.ResultName }}{{ $return.ResultName }} {{ end }}{{ if $return.Constant }}string{{ else if $return.Field }}string{{ else if $return.None }}string{{ else if $return.Value }}{{ $return.Value.TypeName }}{{ end }}{{- end }}{{ if $getter.ErrorExpr }}, {{ if (index $getter.Returns 0).ResultName }}err {{ end }}error{{ end }}) {
+	return {{ range $i, $return := $getter.Returns }}{{ if $i }}, {{ end }}{{ if $return.Constant }}{{ if $return.Reference }}{{ $return.Constant.Name }}{{ else }}"{{ $return.Constant.Value }}"{{ end }}{{ else if $return.Field }}"{{ $return.Field.Value }}"{{ else if $return.None }}"{{ $return.None.Value }}" /* {{ $return.None.Name }} */{{ else if $return.Value }} _struct.{{ $return.Value.FieldName }}{{ end }}{{ end }}{{ if $getter.ErrorExpr }}, {{ $getter.ErrorExpr }}{{ end }}
 }
 
 {{- end }}
 {{- end }}
+{{- end }}
+{{- range $mapKeys := .Package.MapKeys }}
+// Keys of the {{ $mapKeys.Name }} map
+{{ $.Config.Output.Declaration }} (
+{{- range $constant := $mapKeys.Constants }}
+	{{ $constant.Name }} = "{{ $constant.Value }}"
+{{- end }}
+)
+{{- end }}
 {{- end }}
\ No newline at end of file
@@ -3,7 +3,9 @@ package constago
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 
 	v "github.com/cohesivestack/valgo"
@@ -21,16 +23,19 @@ type Config struct {
 func (c *Config) validate() error {
 	val := v.
 		In("input", c.Input.validate()).
-		In("output", c.Output.validate()).
+		In("output", c.Output.validate(len(c.Getters) > 0)).
 		Do(func(val *v.Validation) {
 			for i, element := range c.Elements {
 				val.InRow("elements", i, element.validate())
 			}
 		}).
 		Do(func(val *v.Validation) {
-			elements := make([]string, len(c.Elements))
-			for i, element := range c.Elements {
-				elements[i] = element.Name
+			// Elements in doc mode produce nothing a getter can return
+			elements := make([]string, 0, len(c.Elements))
+			for _, element := range c.Elements {
+				if element.Output.Mode != OutputModeDoc {
+					elements = append(elements, element.Name)
+				}
 			}
 			for i, getter := range c.Getters {
 				val.InRow("getters", i, getter.validate(val.IsValid("elements"), elements))
@@ -51,15 +56,95 @@ type ConfigInput struct {
 
 	Dir string `yaml:"dir"`
 
+	// ResolveTypes enables using the go toolchain to resolve the package
+	// names of the types returned by :value getters
+	ResolveTypes *bool `yaml:"resolve_types"`
+
+	// ResolveCache is the directory persisting the package names resolved
+	// with the go toolchain across runs
+	ResolveCache string `yaml:"resolve_cache"`
+
+	// FastSkip skips parsing the files that can't declare structs
+	FastSkip *bool `yaml:"fast_skip"`
+
+	// RequireModule skips the files outside any Go module, whose packages
+	// can't be resolved
+	RequireModule *bool `yaml:"require_module"`
+
+	// WarnEmptyStructs records a scan error for included structs yielding no
+	// output, and FailEmptyStructs fails the run
+	WarnEmptyStructs *bool `yaml:"warn_empty_structs"`
+	FailEmptyStructs *bool `yaml:"fail_empty_structs"`
+
+	// TagCaseInsensitive matches tag keys ignoring case, e.g. JSON:"name" for json
+	TagCaseInsensitive *bool `yaml:"tag_case_insensitive"`
+
+	// IncludeMapVars generates constants for the string keys of package-level
+	// map literals, e.g. var colors = map[string]int{"red": 1}
+	IncludeMapVars *bool `yaml:"include_map_vars"`
+
+	// IncludeReferenced includes the structs of the package referenced by the
+	// fields of included structs, e.g. Item for Items []Item
+	IncludeReferenced *bool `yaml:"include_referenced"`
+
 	Struct ConfigInputStruct `yaml:"struct"`
 	Field  ConfigInputField  `yaml:"field"`
 }
 
+func (c *ConfigInput) isResolveTypes() bool {
+	return c.ResolveTypes == nil || *c.ResolveTypes
+}
+
+func (c *ConfigInput) isFastSkip() bool {
+	return c.FastSkip != nil && *c.FastSkip
+}
+
+func (c *ConfigInput) isRequireModule() bool {
+	return c.RequireModule != nil && *c.RequireModule
+}
+
+func (c *ConfigInput) isWarnEmptyStructs() bool {
+	return c.WarnEmptyStructs != nil && *c.WarnEmptyStructs
+}
+
+func (c *ConfigInput) isFailEmptyStructs() bool {
+	return c.FailEmptyStructs != nil && *c.FailEmptyStructs
+}
+
+func (c *ConfigInput) isIncludeMapVars() bool {
+	return c.IncludeMapVars != nil && *c.IncludeMapVars
+}
+
+func (c *ConfigInput) isIncludeReferenced() bool {
+	return c.IncludeReferenced != nil && *c.IncludeReferenced
+}
+
+func (c *ConfigInput) isTagCaseInsensitive() bool {
+	return c.TagCaseInsensitive != nil && *c.TagCaseInsensitive
+}
+
 type ConfigInputStruct struct {
 	Explicit          *bool  `yaml:"explicit"`
 	IncludeUnexported *bool  `yaml:"include_unexported"`
+	IncludeAnonymous  *bool  `yaml:"include_anonymous"`
 	Only              string `yaml:"only"`
 	Except            string `yaml:"except"`
+	RequireFieldTag   string `yaml:"require_field_tag"`
+
+	// StripPrefix and StripSuffix are removed from the struct names used in
+	// generated identifiers, e.g. User for UserModel
+	StripPrefix string `yaml:"strip_prefix"`
+	StripSuffix string `yaml:"strip_suffix"`
+}
+
+// identifierName returns the struct name used in generated identifiers, with
+// strip_prefix and strip_suffix removed unless nothing would be left
+func (c *ConfigInputStruct) identifierName(name string) string {
+	stripped := strings.TrimSuffix(strings.TrimPrefix(name, c.StripPrefix), c.StripSuffix)
+	if stripped == "" {
+		return name
+	}
+	return stripped
 }
 
 func (c *ConfigInputStruct) isExplicit() bool {
@@ -70,11 +155,23 @@ func (c *ConfigInputStruct) isIncludeUnexported() bool {
 	return c.IncludeUnexported != nil && *c.IncludeUnexported
 }
 
+func (c *ConfigInputStruct) isIncludeAnonymous() bool {
+	return c.IncludeAnonymous != nil && *c.IncludeAnonymous
+}
+
 type ConfigInputField struct {
 	Explicit          *bool  `yaml:"explicit"`
 	IncludeUnexported *bool  `yaml:"include_unexported"`
 	Only              string `yaml:"only"`
 	Except            string `yaml:"except"`
+
+	// SkipFirst and SkipLast drop the first and last includable fields
+	SkipFirst int `yaml:"skip_first"`
+	SkipLast  int `yaml:"skip_last"`
+
+	// FlattenEmbedded includes the fields promoted from embedded structs
+	// declared in the same file, embedded by value or by pointer
+	FlattenEmbedded *bool `yaml:"flatten_embedded"`
 }
 
 func (c *ConfigInputField) isExplicit() bool {
@@ -85,6 +182,10 @@ func (c *ConfigInputField) isIncludeUnexported() bool {
 	return c.IncludeUnexported != nil && *c.IncludeUnexported
 }
 
+func (c *ConfigInputField) isFlattenEmbedded() bool {
+	return c.FlattenEmbedded != nil && *c.FlattenEmbedded
+}
+
 func (c *ConfigInput) validate() *v.Validation {
 	isValidSourcePatterns := func(val *v.Validation, field string, sources []string) {
 		for i, source := range sources {
@@ -99,6 +200,7 @@ func (c *ConfigInput) validate() *v.Validation {
 				v.BoolP(c.Struct.IncludeUnexported, "include_unexported").Not().Nil(),
 				v.String(c.Struct.Only, "only").Blank().Or().Passing(isValidRegex, validRegexErrorMessage),
 				v.String(c.Struct.Except, "except").Blank().Or().Passing(isValidRegex, validRegexErrorMessage),
+				v.String(c.Struct.RequireFieldTag, "require_field_tag").Blank().Or().Passing(isValidGoIdentifier, validGoIdentifierErrorMessage),
 			),
 		).
 		Do(func(val *v.Validation) {
@@ -111,45 +213,330 @@ func (c *ConfigInput) validate() *v.Validation {
 				v.BoolP(c.Field.IncludeUnexported, "include_unexported").Not().Nil(),
 				v.String(c.Field.Only, "only").Blank().Or().Passing(isValidRegex, validRegexErrorMessage),
 				v.String(c.Field.Except, "except").Blank().Or().Passing(isValidRegex, validRegexErrorMessage),
+				v.Int(c.Field.SkipFirst, "skip_first").GreaterOrEqualTo(0),
+				v.Int(c.Field.SkipLast, "skip_last").GreaterOrEqualTo(0),
 			),
 		)
 }
 
 // config.output
 type ConfigOutput struct {
-	FileName string `yaml:"file_name"`
+	// Format of the generated files, Go code or Markdown documentation
+	Format OutputFormatType `yaml:"format"`
+
+	FileName        string `yaml:"file_name"`
+	PathTemplate    string `yaml:"path_template"`
+	PackageDoc      string `yaml:"package_doc"`
+	GeneratedSuffix string `yaml:"generated_suffix"`
+	PackageName     string `yaml:"package_name"`
+	EmitSourceInfo  *bool  `yaml:"emit_source_info"`
+	EmitSourceHash  *bool  `yaml:"emit_source_hash"`
+
+	// Templates replace the built-in template, parsed together so they can
+	// use each other, executing TemplateEntry to generate each file
+	Templates     []string `yaml:"templates"`
+	TemplateEntry string   `yaml:"template_entry"`
+
+	EmitStructConstant *bool `yaml:"emit_struct_constant"`
+	EmitEmptyStructs   *bool `yaml:"emit_empty_structs"`
+
+	// EmitJSONType emits a constant with the JSON type of each field
+	EmitJSONType *bool `yaml:"emit_json_type"`
+
+	// EmitFieldIndex emits a constant with the reflect index of each field
+	EmitFieldIndex *bool `yaml:"emit_field_index"`
+
+	// AnnotateOptions comments the constants with the options of their tag,
+	// e.g. omitempty
+	AnnotateOptions *bool `yaml:"annotate_options"`
+
+	// EmitSourceTag comments the constants with the key of the tag their
+	// value is taken from, among the keys of tag_priority
+	EmitSourceTag *bool `yaml:"emit_source_tag"`
+
+	FieldOrder FieldOrderType `yaml:"field_order"`
+	ConstBlock ConstBlockType `yaml:"const_block"`
+
+	// GroupByTag names a tag holding the category of each field, grouping
+	// the constants of a struct by category under a comment
+	GroupByTag string `yaml:"group_by_tag"`
+
+	// Declaration is the keyword declaring the generated constants, var
+	// allowing values that aren't constant expressions
+	Declaration DeclarationType `yaml:"declaration"`
+
+	// IdentifierCase reformats every generated identifier last, whatever the
+	// formats of the elements and getters
+	IdentifierCase ConstantFormatType `yaml:"identifier_case"`
+
+	// MaxIdentifierLength truncates the longer generated identifiers, ending
+	// them with a hash of the full name so they stay unique. 0 disables it.
+	MaxIdentifierLength int `yaml:"max_identifier_length"`
+
+	// SplitVisibility separates the output of unexported fields, which gets
+	// unexported identifiers
+	SplitVisibility *bool `yaml:"split_visibility"`
+
+	// SplitExported writes the unexported declarations apart from the
+	// exported ones, in the file named by internalFileName
+	SplitExported *bool `yaml:"split_exported"`
+
+	ExtraImports []string `yaml:"extra_imports"`
+
+	// Goimports removes unused imports from the generated files and adds
+	// missing ones, formatting them like goimports
+	Goimports *bool `yaml:"goimports"`
+
+	// Indent of the generated files, "tab" as gofmt or "spaces:N" to indent
+	// them with N spaces once formatted
+	Indent string `yaml:"indent"`
+
+	MergeMarkers *bool `yaml:"merge_markers"`
+
+	// Force rewrites the output files even when their content is unchanged
+	Force *bool `yaml:"force"`
+
+	// Strict fails on types of :value getters that can't be resolved
+	Strict *bool `yaml:"strict"`
+
+	// Parallel generates the packages concurrently, OutputWriter
+	// implementations must then be safe for concurrent use
+	Parallel *bool `yaml:"parallel"`
+}
+
+// isGeneratedFile reports whether a file was produced by the generator, so
+// it must not be scanned again
+func (c *ConfigOutput) isGeneratedFile(path string) bool {
+	name := filepath.Base(path)
+	if name == c.FileName {
+		return true
+	}
+	if c.isSplitExported() && name == c.internalFileName() {
+		return true
+	}
+	return !isStringBlank(c.GeneratedSuffix) && strings.HasSuffix(name, c.GeneratedSuffix)
+}
+
+func (c *ConfigOutput) isEmitSourceInfo() bool {
+	return c.EmitSourceInfo != nil && *c.EmitSourceInfo
+}
+
+func (c *ConfigOutput) isSplitVisibility() bool {
+	return c.SplitVisibility != nil && *c.SplitVisibility
+}
+
+func (c *ConfigOutput) isEmitSourceTag() bool {
+	return c.EmitSourceTag != nil && *c.EmitSourceTag
+}
+
+func (c *ConfigOutput) isAnnotateOptions() bool {
+	return c.AnnotateOptions != nil && *c.AnnotateOptions
+}
+
+func (c *ConfigOutput) isParallel() bool {
+	return c.Parallel != nil && *c.Parallel
+}
+
+func (c *ConfigOutput) isSplitExported() bool {
+	return c.SplitExported != nil && *c.SplitExported
+}
+
+// internalFileName returns the name of the file holding the unexported
+// declarations with output.split_exported, e.g. constago_internal_gen.go
+// for constago_gen.go
+func (c *ConfigOutput) internalFileName() string {
+	for _, suffix := range []string{"_gen.go", ".gen.go"} {
+		if base, ok := strings.CutSuffix(c.FileName, suffix); ok {
+			return base + "_internal" + suffix
+		}
+	}
+	return strings.TrimSuffix(c.FileName, ".go") + "_internal.go"
+}
+
+func (c *ConfigOutput) isEmitSourceHash() bool {
+	return c.EmitSourceHash != nil && *c.EmitSourceHash
+}
+
+func (c *ConfigOutput) isGoimports() bool {
+	return c.Goimports != nil && *c.Goimports
+}
+
+// indentSpaces returns the number of spaces of output.indent, 0 for tabs
+func (c *ConfigOutput) indentSpaces() int {
+	n, _ := strconv.Atoi(strings.TrimPrefix(c.Indent, "spaces:"))
+	return n
+}
+
+func (c *ConfigOutput) isStrict() bool {
+	return c.Strict != nil && *c.Strict
+}
+
+func (c *ConfigOutput) isForce() bool {
+	return c.Force != nil && *c.Force
+}
+
+func (c *ConfigOutput) isMergeMarkers() bool {
+	return c.MergeMarkers != nil && *c.MergeMarkers
+}
+
+func (c *ConfigOutput) isEmitJSONType() bool {
+	return c.EmitJSONType != nil && *c.EmitJSONType
 }
 
-func (c *ConfigOutput) validate() *v.Validation {
+func (c *ConfigOutput) isEmitFieldIndex() bool {
+	return c.EmitFieldIndex != nil && *c.EmitFieldIndex
+}
+
+func (c *ConfigOutput) isEmitStructConstant() bool {
+	return c.EmitStructConstant != nil && *c.EmitStructConstant
+}
+
+func (c *ConfigOutput) isEmitEmptyStructs() bool {
+	return c.EmitEmptyStructs != nil && *c.EmitEmptyStructs
+}
+
+func (c *ConfigOutput) validate(hasGetters bool) *v.Validation {
 	return v.Is(
-		v.String(c.FileName, "file_name").Not().Blank().MatchingTo(regexp.MustCompile(`^[^/\\]*\.go$`), "{{title}} must be a valid Go filename"),
-	)
+		v.String(c.Format, "format").Blank().Or().InSlice(validOutputFormats, validOutputFormatsErrorMessage),
+		v.String(c.PathTemplate, "path_template").Blank().Or().Passing(isValidTemplate, validTemplateErrorMessage),
+		v.String(c.PackageDoc, "package_doc").Blank().Or().Passing(isValidTemplate, validTemplateErrorMessage),
+		v.String(c.TemplateEntry, "template_entry").Blank().Or().Passing(func(string) bool { return len(c.Templates) > 0 }, validTemplateEntryErrorMessage),
+		v.String(c.GeneratedSuffix, "generated_suffix").Blank().Or().MatchingTo(regexp.MustCompile(`^[^/\\]*\.go$`), "{{title}} must end with .go"),
+		v.String(c.PackageName, "package_name").Blank().Or().Passing(isValidGoIdentifier, validGoIdentifierErrorMessage),
+		v.String(c.FieldOrder, "field_order").Blank().Or().InSlice(validFieldOrders, validFieldOrdersErrorMessage),
+		v.String(c.ConstBlock, "const_block").Blank().Or().InSlice(validConstBlocks, validConstBlocksErrorMessage),
+		v.String(c.GroupByTag, "group_by_tag").Blank().Or().Passing(isValidGoIdentifier, validGoIdentifierErrorMessage),
+		v.String(c.Declaration, "declaration").Blank().Or().InSlice(validDeclarations, validDeclarationsErrorMessage),
+		v.String(c.Indent, "indent").Blank().Or().MatchingTo(regexp.MustCompile(`^(tab|spaces:[1-9][0-9]?)$`), validIndentErrorMessage),
+		v.String(c.IdentifierCase, "identifier_case").Blank().Or().InSlice(validIdentifierCases, validIdentifierCasesErrorMessage),
+		v.Int(c.MaxIdentifierLength, "max_identifier_length").Zero().Or().GreaterOrEqualTo(minMaxIdentifierLength, validMaxIdentifierLengthErrorMessage),
+	).
+		Do(func(val *v.Validation) {
+			for i, entry := range c.ExtraImports {
+				val.InCell("extra_imports", i, v.Is(v.String(entry, "", "Import").Passing(isValidImport, validImportErrorMessage)))
+			}
+			for i, file := range c.Templates {
+				val.InCell("templates", i, v.Is(v.String(file, "", "Template").Not().Blank()))
+			}
+		}).
+		When(c.Format != OutputFormatMarkdown, func(val *v.Validation) {
+			val.Is(v.String(c.FileName, "file_name").Not().Blank().MatchingTo(regexp.MustCompile(`^[^/\\]*\.go$`), "{{title}} must be a valid Go filename"))
+		}).
+		When(c.Format != OutputFormatMarkdown && !isStringBlank(c.PackageName) && isStringBlank(c.PathTemplate), func(val *v.Validation) {
+			val.Is(v.Bool(strings.HasSuffix(c.PackageName, "_test") && strings.HasSuffix(c.FileName, "_test.go"), "package_name").True(validPackageNameInSourceDirErrorMessage))
+		}).
+		When(c.Format == OutputFormatMarkdown, func(val *v.Validation) {
+			val.Is(
+				v.String(c.FileName, "file_name").Not().Blank().MatchingTo(regexp.MustCompile(`^[^/\\]*\.md$`), "{{title}} must be a valid Markdown filename"),
+				v.Bool(c.isMergeMarkers(), "merge_markers").False(validGoFormatOnlyErrorMessage),
+				v.Bool(c.isGoimports(), "goimports").False(validGoFormatOnlyErrorMessage),
+				v.Bool(c.isSplitExported(), "split_exported").False(validGoFormatOnlyErrorMessage),
+				v.Bool(c.indentSpaces() > 0, "indent").False(validGoFormatOnlyErrorMessage),
+			)
+		}).
+		When(hasGetters, func(val *v.Validation) {
+			// Methods can only be declared in the package of their receiver type
+			val.Is(v.String(c.PackageName, "package_name").Blank(validPackageNameWithGettersErrorMessage))
+		})
 }
 
 // config.tags[i]
 type ConfigTag struct {
 	Name string `yaml:"name"`
 
+	// Regular expression; the element only applies to matching struct names
+	StructsMatching string `yaml:"structs_matching"`
+
 	Input  ConfigTagInput  `yaml:"input"`
 	Output ConfigTagOutput `yaml:"output"`
 }
 
 type ConfigTagInput struct {
-	Mode        InputModeType `yaml:"mode"`
-	TagPriority []string      `yaml:"tag_priority"`
+	Mode        InputModeType      `yaml:"mode"`
+	TagPriority []string           `yaml:"tag_priority"`
+	ValueMap    map[string]string  `yaml:"value_map"`
+	Join        ConfigTagInputJoin `yaml:"join"`
+
+	// TagSyntax is how the tag values are parsed, where keyvalue reads the
+	// option named by OptionKey from values like "column:user_name;size:64",
+	// and protobuf the name option of descriptors like "bytes,1,opt,name=id"
+	TagSyntax TagSyntaxType `yaml:"tag_syntax"`
+	OptionKey string        `yaml:"option_key"`
+
+	// ExpandOneof emits a constant per value allowed by the oneof option of
+	// the tags, e.g. validate:"oneof=active banned"
+	ExpandOneof *bool `yaml:"expand_oneof"`
+}
+
+func (c *ConfigTagInput) isExpandOneof() bool {
+	return c.ExpandOneof != nil && *c.ExpandOneof
+}
+
+// ConfigTagInputJoin combines the values of several tags into one value
+type ConfigTagInputJoin struct {
+	Tags      []string `yaml:"tags"`
+	Separator string   `yaml:"separator"`
 }
 
 type ConfigTagOutput struct {
-	Mode      OutputModeType           `yaml:"mode"`
-	Format    ConfigTagOutputFormat    `yaml:"format"`
-	Transform ConfigTagOutputTransform `yaml:"transform"`
+	Mode             OutputModeType           `yaml:"mode"`
+	TypedKey         *bool                    `yaml:"typed_key"`
+	EmitValues       *bool                    `yaml:"emit_values"`
+	CollectionSuffix string                   `yaml:"collection_suffix"`
+	TypeSuffix       *bool                    `yaml:"type_suffix"`
+	EmitNormalized   *bool                    `yaml:"emit_normalized"`
+	Normalization    TransformCaseType        `yaml:"normalization"`
+	EmitBoth         *bool                    `yaml:"emit_both"`
+	StructFieldTags  StructFieldTagsType      `yaml:"struct_field_tags"`
+	NoneName         string                   `yaml:"none_name"`
+	AggregateTo      string                   `yaml:"aggregate_to"`
+	Format           ConfigTagOutputFormat    `yaml:"format"`
+	Transform        ConfigTagOutputTransform `yaml:"transform"`
+}
+
+func (c *ConfigTagOutput) isTypedKey() bool {
+	return c.TypedKey != nil && *c.TypedKey
+}
+
+func (c *ConfigTagOutput) isEmitValues() bool {
+	return c.EmitValues != nil && *c.EmitValues
+}
+
+func (c *ConfigTagOutput) isTypeSuffix() bool {
+	return c.TypeSuffix != nil && *c.TypeSuffix
+}
+
+func (c *ConfigTagOutput) isEmitNormalized() bool {
+	return c.EmitNormalized != nil && *c.EmitNormalized
+}
+
+func (c *ConfigTagOutput) isEmitBoth() bool {
+	return c.EmitBoth != nil && *c.EmitBoth
 }
 
 type ConfigTagOutputFormat struct {
-	Holder ConstantFormatType `yaml:"holder"`
-	Struct ConstantFormatType `yaml:"struct"`
-	Prefix string             `yaml:"prefix"`
-	Suffix string             `yaml:"suffix"`
+	Holder         ConstantFormatType `yaml:"holder"`
+	Struct         ConstantFormatType `yaml:"struct"`
+	Prefix         string             `yaml:"prefix"`
+	PrefixLiteral  *bool              `yaml:"prefix_literal"`
+	Suffix         string             `yaml:"suffix"`
+	IncludePackage *bool              `yaml:"include_package"`
+
+	// OmitStruct leaves the struct name out of the constant identifiers, so
+	// fields of several structs may share a constant
+	OmitStruct *bool `yaml:"omit_struct"`
+}
+
+func (c *ConfigTagOutputFormat) isPrefixLiteral() bool {
+	return c.PrefixLiteral != nil && *c.PrefixLiteral
+}
+
+func (c *ConfigTagOutputFormat) isOmitStruct() bool {
+	return c.OmitStruct != nil && *c.OmitStruct
+}
+
+func (c *ConfigTagOutputFormat) isIncludePackage() bool {
+	return c.IncludePackage != nil && *c.IncludePackage
 }
 
 type ConfigTagOutputTransform struct {
@@ -160,30 +547,57 @@ type ConfigTagOutputTransform struct {
 
 func (c *ConfigTag) validate() *v.Validation {
 	return v.
-		Is(v.String(c.Name, "name").Not().Blank().Passing(isValidGoIdentifier, validGoIdentifierErrorMessage)).
+		Is(
+			v.String(c.Name, "name").Not().Blank().Passing(isValidGoIdentifier, validGoIdentifierErrorMessage),
+			v.String(c.StructsMatching, "structs_matching").Blank().Or().Passing(isValidRegex, validRegexErrorMessage),
+		).
 		In("input", v.
 			Is(
 				v.String(c.Input.Mode, "mode").Not().Blank().InSlice(validNameOrTitleModes, validNameOrTitleModesErrorMessage),
 				v.Int(len(c.Input.TagPriority), "tag_priority").Not().LessThan(1, validIncludeErrorMessage),
+				v.String(c.Input.TagSyntax, "tag_syntax").Blank().Or().InSlice(validTagSyntaxes, validTagSyntaxesErrorMessage),
+				v.Bool(c.Input.isExpandOneof() && c.Output.Mode != OutputModeConstant, "expand_oneof").False(validExpandOneofErrorMessage),
 			).
+			When(c.Input.TagSyntax == TagSyntaxKeyValue, func(val *v.Validation) {
+				val.Is(v.String(c.Input.OptionKey, "option_key").Not().Blank())
+			}).
 			Do(func(val *v.Validation) {
 				for i, tag := range c.Input.TagPriority {
 					val.InCell("tag_priority", i, v.Is(v.String(tag, "", "Tag priority").Passing(isValidGoIdentifier, validGoIdentifierErrorMessage)))
 				}
+				for i, tag := range c.Input.Join.Tags {
+					val.In("join", v.InCell("tags", i, v.Is(v.String(tag, "", "Tag").Passing(isValidGoIdentifier, validGoIdentifierErrorMessage))))
+				}
+				for fieldName := range c.Input.ValueMap {
+					val.In("value_map", v.Is(v.String(fieldName, fieldName, "Field name").Passing(isValidGoIdentifier, validGoIdentifierErrorMessage)))
+				}
 			}),
 		).
 		In("output", v.
-			Is(v.String(c.Output.Mode, "mode").Not().Blank().InSlice(validOutputModes, validOutputModesErrorMessage)).
+			Is(
+				v.String(c.Output.Mode, "mode").Not().Blank().InSlice(validOutputModes, validOutputModesErrorMessage),
+				v.String(c.Output.CollectionSuffix, "collection_suffix").Empty().Or().Passing(isValidGoIdentifier, validGoIdentifierErrorMessage),
+				v.String(c.Output.Normalization, "normalization").Blank().Or().InSlice(validNormalizations, validNormalizationsErrorMessage),
+				v.String(c.Output.StructFieldTags, "struct_field_tags").Blank().Or().InSlice(validStructFieldTags, validStructFieldTagsErrorMessage),
+				v.Bool(c.Output.isEmitValues() && !c.Output.isTypedKey(), "emit_values").False(validEmitValuesErrorMessage),
+				v.Bool(!isStringBlank(c.Output.AggregateTo) && (c.Output.Mode != OutputModeConstant || c.Output.isTypedKey()), "aggregate_to").False(validAggregateToErrorMessage),
+				v.String(c.Output.NoneName, "none_name").Not().Passing(func(name string) bool { return strings.Contains(name, "*/") || strings.ContainsAny(name, "\r\n") }, validNoneNameErrorMessage),
+			).
 			In("format", v.Is(
 				v.String(c.Output.Format.Holder, "holder").Not().Blank().InSlice(validConstantFormats, validConstantFormatsErrorMessage),
 				v.String(c.Output.Format.Struct, "struct").Not().Blank().InSlice(validConstantFormats, validConstantFormatsErrorMessage),
 				v.String(c.Output.Format.Prefix, "prefix").Empty().Or().Passing(isValidGoIdentifier, validGoIdentifierErrorMessage),
 				v.String(c.Output.Format.Suffix, "suffix").Empty().Or().Passing(isValidGoIdentifier, validGoIdentifierErrorMessage),
 			)).
-			In("transform", v.Is(
-				v.BoolP(c.Output.Transform.TagValues, "tag_values").Not().Nil(),
-				v.String(c.Output.Transform.ValueCase, "value_case").Not().Blank().InSlice(validTransformCases, validTransformCasesErrorMessage),
-			)),
+			In("transform", v.
+				Is(v.BoolP(c.Output.Transform.TagValues, "tag_values").Not().Nil()).
+				When(!isCustomTransform(c.Output.Transform.ValueCase), func(val *v.Validation) {
+					val.Is(v.String(c.Output.Transform.ValueCase, "value_case").Not().Blank().InSlice(validTransformCases, validTransformCasesErrorMessage))
+				}).
+				When(isCustomTransform(c.Output.Transform.ValueCase), func(val *v.Validation) {
+					val.Is(v.String(c.Output.Transform.ValueCase, "value_case").Passing(isRegisteredTransform, validCustomTransformErrorMessage))
+				}),
+			),
 		)
 }
 
@@ -192,18 +606,46 @@ type ConfigGetter struct {
 	Name    string             `yaml:"name"`
 	Returns []string           `yaml:"returns"`
 	Output  ConfigGetterOutput `yaml:"output"`
+
+	// StructsMatching restricts the getter to the structs matching the regex
+	StructsMatching string `yaml:"structs_matching"`
 }
 
 type ConfigGetterOutput struct {
-	Prefix string             `yaml:"prefix"`
-	Suffix string             `yaml:"suffix"`
-	Format ConstantFormatType `yaml:"format"`
+	Prefix        string             `yaml:"prefix"`
+	PrefixLiteral *bool              `yaml:"prefix_literal"`
+	Suffix        string             `yaml:"suffix"`
+	Format        ConstantFormatType `yaml:"format"`
+	PromoteNone   *bool              `yaml:"promote_none"`
+	ResultNames   []string           `yaml:"result_names"`
+
+	// ErrorReturn appends an error to the getter results, returning the Go
+	// expression produced by the ErrorExpr template, nil by default
+	ErrorReturn *bool  `yaml:"error_return"`
+	ErrorExpr   string `yaml:"error_expr"`
+
+	// ReceiverType declares the getter methods on another named type of the
+	// package, e.g. a wrapper embedding the struct, instead of the struct
+	ReceiverType string `yaml:"receiver_type"`
+}
+
+func (c *ConfigGetterOutput) isPrefixLiteral() bool {
+	return c.PrefixLiteral != nil && *c.PrefixLiteral
+}
+
+func (c *ConfigGetterOutput) isErrorReturn() bool {
+	return c.ErrorReturn != nil && *c.ErrorReturn
+}
+
+func (c *ConfigGetterOutput) isPromoteNone() bool {
+	return c.PromoteNone != nil && *c.PromoteNone
 }
 
 func (c *ConfigGetter) validate(validElements bool, elements []string) *v.Validation {
 	return v.
 		Is(v.String(c.Name, "name").Not().Blank().Passing(isValidGoIdentifier, validGoIdentifierErrorMessage)).
 		Is(v.Int(len(c.Returns), "returns").Not().LessThan(1, validIncludeErrorMessage)).
+		Is(v.String(c.StructsMatching, "structs_matching").Blank().Or().Passing(isValidRegex, validRegexErrorMessage)).
 		When(validElements, func(val *v.Validation) {
 			_elements := append(elements, ":value")
 			for i, element := range c.Returns {
@@ -229,10 +671,61 @@ func (c *ConfigGetter) validate(validElements bool, elements []string) *v.Valida
 				v.String(c.Output.Prefix, "prefix").Empty().Or().Passing(isValidGoIdentifier, validGoIdentifierErrorMessage),
 				v.String(c.Output.Suffix, "suffix").Empty().Or().Passing(isValidGoIdentifier, validGoIdentifierErrorMessage),
 				v.String(c.Output.Format, "format").Not().Blank().InSlice(validConstantFormats, validConstantFormatsErrorMessage),
-			),
+				v.Int(len(c.Output.ResultNames), "result_names").Zero().Or().EqualTo(len(c.Returns), validResultNamesErrorMessage),
+				v.String(c.Output.ErrorExpr, "error_expr").Blank().Or().Passing(isValidTemplate, validTemplateErrorMessage),
+				v.String(c.Output.ReceiverType, "receiver_type").Blank().Or().Passing(isValidGoIdentifier, validGoIdentifierErrorMessage),
+			).
+			When(!isStringBlank(c.Output.ReceiverType), func(val *v.Validation) {
+				val.Is(v.String(c.StructsMatching, "receiver_type").Not().Blank(validReceiverTypeErrorMessage))
+			}).
+			When(!c.Output.isErrorReturn(), func(val *v.Validation) {
+				val.Is(v.String(c.Output.ErrorExpr, "error_expr").Blank(validErrorExprErrorMessage))
+			}).
+			Do(func(val *v.Validation) {
+				// The receiver is always named _struct, and the error result err
+				reserved := []string{"_struct"}
+				if c.Output.isErrorReturn() {
+					reserved = append(reserved, "err")
+				}
+				for i, name := range c.Output.ResultNames {
+					val.InCell("result_names", i, v.Is(v.String(name, "", "Result name").
+						Passing(isValidGoIdentifier, validGoIdentifierErrorMessage).
+						Not().InSlice(reserved, validReservedResultNameErrorMessage)))
+				}
+			}),
 		)
 }
 
+// appliesToStruct reports whether the element applies to the struct name
+func (c *ConfigTag) appliesToStruct(structName string) bool {
+	return matchesStructName(c.StructsMatching, structName)
+}
+
+// appliesToStruct reports whether the getter applies to the struct name
+func (c *ConfigGetter) appliesToStruct(structName string) bool {
+	return matchesStructName(c.StructsMatching, structName)
+}
+
+// matchesStructName reports whether the struct name matches the pattern of a
+// structs_matching option, where a blank pattern matches every struct
+func matchesStructName(pattern string, structName string) bool {
+	if strings.TrimSpace(pattern) == "" {
+		return true
+	}
+	matched, err := regexp.MatchString(pattern, structName)
+	return err == nil && matched
+}
+
+// findElement returns the element with the given name, or nil if not found
+func (c *Config) findElement(name string) *ConfigTag {
+	for i := range c.Elements {
+		if c.Elements[i].Name == name {
+			return &c.Elements[i]
+		}
+	}
+	return nil
+}
+
 // LoadConfig loads and parses the configuration from a YAML file
 func LoadConfig(filename string) (*Config, error) {
 	data, err := os.ReadFile(filename)
@@ -272,6 +765,14 @@ func NewConfig(config *Config) (*Config, error) {
 	return config, nil
 }
 
+// DefaultConfig returns an empty configuration with all the defaults applied,
+// which is what NewConfig produces before validating
+func DefaultConfig() *Config {
+	config := &Config{}
+	config.setDefaults()
+	return config
+}
+
 // setDefaults sets default values for configuration fields
 func (config *Config) setDefaults() {
 	// Input defaults
@@ -284,22 +785,118 @@ func (config *Config) setDefaults() {
 	if len(config.Input.Exclude) == 0 {
 		config.Input.Exclude = []string{"**/*_test.go"}
 	}
+	if config.Input.ResolveTypes == nil {
+		config.Input.ResolveTypes = boolPtr(true)
+	}
+	if config.Input.FastSkip == nil {
+		config.Input.FastSkip = boolPtr(false)
+	}
+	if config.Input.RequireModule == nil {
+		config.Input.RequireModule = boolPtr(false)
+	}
+	if config.Input.WarnEmptyStructs == nil {
+		config.Input.WarnEmptyStructs = boolPtr(false)
+	}
+	if config.Input.FailEmptyStructs == nil {
+		config.Input.FailEmptyStructs = boolPtr(false)
+	}
+	if config.Input.TagCaseInsensitive == nil {
+		config.Input.TagCaseInsensitive = boolPtr(false)
+	}
+	if config.Input.IncludeMapVars == nil {
+		config.Input.IncludeMapVars = boolPtr(false)
+	}
+	if config.Input.IncludeReferenced == nil {
+		config.Input.IncludeReferenced = boolPtr(false)
+	}
 	if config.Input.Struct.Explicit == nil {
 		config.Input.Struct.Explicit = boolPtr(false)
 	}
 	if config.Input.Struct.IncludeUnexported == nil {
 		config.Input.Struct.IncludeUnexported = boolPtr(false)
 	}
+	if config.Input.Struct.IncludeAnonymous == nil {
+		config.Input.Struct.IncludeAnonymous = boolPtr(false)
+	}
 	if config.Input.Field.Explicit == nil {
 		config.Input.Field.Explicit = boolPtr(false)
 	}
 	if config.Input.Field.IncludeUnexported == nil {
 		config.Input.Field.IncludeUnexported = boolPtr(false)
 	}
+	if config.Input.Field.FlattenEmbedded == nil {
+		config.Input.Field.FlattenEmbedded = boolPtr(false)
+	}
 
 	// Output defaults
+	if config.Output.Format == "" {
+		config.Output.Format = OutputFormatGo
+	}
 	if isStringBlank(config.Output.FileName) {
 		config.Output.FileName = "constago.gen.go"
+		if config.Output.Format == OutputFormatMarkdown {
+			config.Output.FileName = "constago.gen.md"
+		}
+	}
+	if config.Output.EmitSourceInfo == nil {
+		config.Output.EmitSourceInfo = boolPtr(false)
+	}
+	if config.Output.EmitSourceHash == nil {
+		config.Output.EmitSourceHash = boolPtr(false)
+	}
+	if config.Output.Strict == nil {
+		config.Output.Strict = boolPtr(false)
+	}
+	if config.Output.EmitJSONType == nil {
+		config.Output.EmitJSONType = boolPtr(false)
+	}
+	if config.Output.EmitFieldIndex == nil {
+		config.Output.EmitFieldIndex = boolPtr(false)
+	}
+	if config.Output.AnnotateOptions == nil {
+		config.Output.AnnotateOptions = boolPtr(false)
+	}
+	if config.Output.EmitSourceTag == nil {
+		config.Output.EmitSourceTag = boolPtr(false)
+	}
+	if config.Output.SplitVisibility == nil {
+		config.Output.SplitVisibility = boolPtr(false)
+	}
+	if config.Output.SplitExported == nil {
+		config.Output.SplitExported = boolPtr(false)
+	}
+	if config.Output.Parallel == nil {
+		config.Output.Parallel = boolPtr(false)
+	}
+	if config.Output.Force == nil {
+		config.Output.Force = boolPtr(false)
+	}
+	if config.Output.Goimports == nil {
+		config.Output.Goimports = boolPtr(false)
+	}
+	if config.Output.MergeMarkers == nil {
+		config.Output.MergeMarkers = boolPtr(false)
+	}
+	if config.Output.EmitStructConstant == nil {
+		config.Output.EmitStructConstant = boolPtr(false)
+	}
+	if config.Output.EmitEmptyStructs == nil {
+		config.Output.EmitEmptyStructs = boolPtr(false)
+	}
+	if config.Output.FieldOrder == "" {
+		config.Output.FieldOrder = FieldOrderSource
+	}
+	if config.Output.ConstBlock == "" {
+		config.Output.ConstBlock = ConstBlockGroup
+	}
+	if config.Output.Indent == "" {
+		config.Output.Indent = "tab"
+	}
+	if config.Output.Declaration == "" {
+		config.Output.Declaration = DeclarationConst
+	}
+	if config.Output.IdentifierCase == "" {
+		config.Output.IdentifierCase = IdentifierCaseNone
 	}
 
 	for i := range config.Elements {
@@ -311,21 +908,57 @@ func (config *Config) setDefaults() {
 		if len(element.Input.TagPriority) == 0 {
 			element.Input.TagPriority = []string{"field", "json", "xml", "yaml", "toml", "sql"}
 		}
+		if element.Input.TagSyntax == "" {
+			element.Input.TagSyntax = TagSyntaxStandard
+		}
+		if element.Input.ExpandOneof == nil {
+			element.Input.ExpandOneof = boolPtr(false)
+		}
 		if element.Output.Mode == "" {
 			element.Output.Mode = OutputModeConstant
 		}
+		if element.Output.TypedKey == nil {
+			element.Output.TypedKey = boolPtr(false)
+		}
+		if element.Output.EmitValues == nil {
+			element.Output.EmitValues = boolPtr(false)
+		}
+		if element.Output.TypeSuffix == nil {
+			element.Output.TypeSuffix = boolPtr(false)
+		}
+		if element.Output.EmitNormalized == nil {
+			element.Output.EmitNormalized = boolPtr(false)
+		}
+		if element.Output.Normalization == "" {
+			element.Output.Normalization = TransformCaseLower
+		}
+		if element.Output.EmitBoth == nil {
+			element.Output.EmitBoth = boolPtr(false)
+		}
+		if element.Output.StructFieldTags == "" {
+			element.Output.StructFieldTags = StructFieldTagsNone
+		}
 		if element.Output.Format.Holder == "" {
 			element.Output.Format.Holder = ConstantFormatPascal
 		}
 		if element.Output.Format.Struct == "" {
 			element.Output.Format.Struct = ConstantFormatPascal
 		}
+		if element.Output.Format.PrefixLiteral == nil {
+			element.Output.Format.PrefixLiteral = boolPtr(false)
+		}
 		if isStringBlank(element.Output.Format.Prefix) {
 			element.Output.Format.Prefix = element.Name
 		}
 		if isStringBlank(element.Output.Format.Suffix) {
 			element.Output.Format.Suffix = ""
 		}
+		if element.Output.Format.IncludePackage == nil {
+			element.Output.Format.IncludePackage = boolPtr(false)
+		}
+		if element.Output.Format.OmitStruct == nil {
+			element.Output.Format.OmitStruct = boolPtr(false)
+		}
 		if element.Output.Transform.TagValues == nil {
 			element.Output.Transform.TagValues = boolPtr(false)
 		}
@@ -340,11 +973,20 @@ func (config *Config) setDefaults() {
 	for i := range config.Getters {
 		getter := &config.Getters[i]
 
+		if getter.Output.PrefixLiteral == nil {
+			getter.Output.PrefixLiteral = boolPtr(false)
+		}
 		if isStringBlank(getter.Output.Prefix) {
 			getter.Output.Prefix = getter.Name
 		}
 		if isStringBlank(getter.Output.Format) {
 			getter.Output.Format = ConstantFormatPascal
 		}
+		if getter.Output.PromoteNone == nil {
+			getter.Output.PromoteNone = boolPtr(false)
+		}
+		if getter.Output.ErrorReturn == nil {
+			getter.Output.ErrorReturn = boolPtr(false)
+		}
 	}
 }
@@ -458,6 +458,197 @@ func TestConfigValidate(t *testing.T) {
 				"output.file_name": {"File name must be a valid Go filename"},
 			},
 		},
+		{
+			name: "invalid output path template",
+			config: &Config{
+				Output: ConfigOutput{
+					FileName:     "test.go",
+					PathTemplate: "{{ .Package.Path }/gen.go",
+				},
+			},
+			errorContains: map[string][]string{
+				"output.path_template": {"Path template must be a valid template"},
+			},
+		},
+		{
+			name: "invalid output package doc",
+			config: &Config{
+				Output: ConfigOutput{
+					FileName:   "test.go",
+					PackageDoc: "Package {{ .PackageName } keys",
+				},
+			},
+			errorContains: map[string][]string{
+				"output.package_doc": {"Package doc must be a valid template"},
+			},
+		},
+		{
+			name: "invalid output indent",
+			config: &Config{
+				Output: ConfigOutput{
+					FileName: "test.go",
+					Indent:   "spaces",
+				},
+			},
+			errorContains: map[string][]string{
+				"output.indent": {"\"spaces\" is not a valid Indent, must be tab or spaces:N"},
+			},
+		},
+		{
+			name: "output template entry without templates",
+			config: &Config{
+				Output: ConfigOutput{
+					FileName:      "test.go",
+					TemplateEntry: "base.tpl",
+					Templates:     []string{},
+				},
+			},
+			errorContains: map[string][]string{
+				"output.template_entry": {"Template entry requires output.templates"},
+			},
+		},
+		{
+			name: "markdown output format with go options",
+			config: &Config{
+				Output: ConfigOutput{
+					Format:        OutputFormatMarkdown,
+					FileName:      "docs.go",
+					MergeMarkers:  boolPtr(true),
+					Goimports:     boolPtr(true),
+					SplitExported: boolPtr(true),
+				},
+			},
+			errorContains: map[string][]string{
+				"output.file_name":      {"File name must be a valid Markdown filename"},
+				"output.merge_markers":  {"Merge markers can't be combined with the markdown format"},
+				"output.goimports":      {"Goimports can't be combined with the markdown format"},
+				"output.split_exported": {"Split exported can't be combined with the markdown format"},
+			},
+		},
+		{
+			name: "invalid output max identifier length",
+			config: &Config{
+				Output: ConfigOutput{
+					FileName:            "test.go",
+					MaxIdentifierLength: 8,
+				},
+			},
+			errorContains: map[string][]string{
+				"output.max_identifier_length": {"Max identifier length must be 0 or at least 16"},
+			},
+		},
+		{
+			name: "invalid output extra imports",
+			config: &Config{
+				Output: ConfigOutput{
+					FileName:     "test.go",
+					ExtraImports: []string{"fmt", "my-alias fmt", ""},
+				},
+			},
+			errorContains: map[string][]string{
+				"output.extra_imports[1]": {"\"my-alias fmt\" is not a valid import, must be \"path\" or \"alias path\""},
+				"output.extra_imports[2]": {"\"\" is not a valid import, must be \"path\" or \"alias path\""},
+			},
+		},
+		{
+			name: "invalid output generated suffix",
+			config: &Config{
+				Output: ConfigOutput{
+					FileName:        "test.go",
+					GeneratedSuffix: ".generated",
+				},
+			},
+			errorContains: map[string][]string{
+				"output.generated_suffix": {"Generated suffix must end with .go"},
+			},
+		},
+		{
+			name: "invalid output package name - not a Go identifier",
+			config: &Config{
+				Output: ConfigOutput{
+					FileName:    "test.go",
+					PackageName: "my-pkg",
+				},
+			},
+			errorContains: map[string][]string{
+				"output.package_name": {"\"my-pkg\" is not a valid Go identifier"},
+			},
+		},
+		{
+			name: "invalid output package name - written next to the sources",
+			config: &Config{
+				Output: ConfigOutput{
+					FileName:    "keys_gen.go",
+					PackageName: "keys",
+				},
+			},
+			errorContains: map[string][]string{
+				"output.package_name": {"Package name can only name an external test package, ending with _test and written to a _test.go file_name, unless path_template writes the files elsewhere, since a folder holds a single package"},
+			},
+		},
+		{
+			name: "invalid output package name - combined with getters",
+			config: &Config{
+				Output: ConfigOutput{
+					FileName:    "test.go",
+					PackageName: "model_test",
+				},
+				Getters: []ConfigGetter{
+					{
+						Name:    "validator",
+						Returns: []string{":value"},
+					},
+				},
+			},
+			errorContains: map[string][]string{
+				"output.package_name": {"Package name can't be set when getters are configured, since methods must be declared in the package of the struct"},
+			},
+		},
+		{
+			name: "invalid getter result names",
+			config: &Config{
+				Output: ConfigOutput{
+					FileName: "test.go",
+				},
+				Getters: []ConfigGetter{
+					{
+						Name:    "validator",
+						Returns: []string{":value"},
+						Output: ConfigGetterOutput{
+							Format:      ConstantFormatPascal,
+							ResultNames: []string{"value", "1title"},
+						},
+					},
+				},
+			},
+			errorContains: map[string][]string{
+				"getters[0].output.result_names":    {"Result names must have one name per return"},
+				"getters[0].output.result_names[1]": {"\"1title\" is not a valid Go identifier"},
+			},
+		},
+		{
+			name: "reserved getter result names",
+			config: &Config{
+				Output: ConfigOutput{
+					FileName: "test.go",
+				},
+				Getters: []ConfigGetter{
+					{
+						Name:    "validator",
+						Returns: []string{":value", "json"},
+						Output: ConfigGetterOutput{
+							Format:      ConstantFormatPascal,
+							ResultNames: []string{"err", "_struct"},
+							ErrorReturn: boolPtr(true),
+						},
+					},
+				},
+			},
+			errorContains: map[string][]string{
+				"getters[0].output.result_names[0]": {"\"err\" is reserved for the receiver or the error result of the getter"},
+				"getters[0].output.result_names[1]": {"\"_struct\" is reserved for the receiver or the error result of the getter"},
+			},
+		},
 		{
 			name: "invalid source pattern - no valid pattern",
 			config: &Config{
@@ -554,7 +745,7 @@ func TestConfigValidate(t *testing.T) {
 				},
 			},
 			errorContains: map[string][]string{
-				"elements[0].input.mode": {"\"invalid\" is not a valid Mode, must be tag, field, or tagThenField"},
+				"elements[0].input.mode": {"\"invalid\" is not a valid Mode, must be tag, field, tagThenField, or type"},
 			},
 		},
 		{
@@ -588,6 +779,144 @@ func TestConfigValidate(t *testing.T) {
 				"elements[0].input.tag_priority": {"Tag priority must have at least one element"},
 			},
 		},
+		{
+			name: "getter error expression without error return",
+			config: &Config{
+				Output: ConfigOutput{
+					FileName: "test.go",
+				},
+				Getters: []ConfigGetter{
+					{
+						Name:    "Get",
+						Returns: []string{":value"},
+						Output: ConfigGetterOutput{
+							Format:    ConstantFormatPascal,
+							ErrorExpr: "{{ .Receiver }}.Validate()",
+						},
+					},
+				},
+			},
+			errorContains: map[string][]string{
+				"getters[0].output.error_expr": {"Error expr requires error_return"},
+			},
+		},
+		{
+			name: "element none name closing the comment",
+			config: &Config{
+				Output: ConfigOutput{
+					FileName: "test.go",
+				},
+				Elements: []ConfigTag{
+					{
+						Name: "title",
+						Output: ConfigTagOutput{
+							Mode:     OutputModeNone,
+							NoneName: "label */",
+						},
+					},
+				},
+			},
+			errorContains: map[string][]string{
+				"elements[0].output.none_name": {"None name can't hold */ or line breaks, since it labels the getter values in a comment"},
+			},
+		},
+		{
+			name: "getter receiver type without structs matching",
+			config: &Config{
+				Output: ConfigOutput{
+					FileName: "test.go",
+				},
+				Elements: []ConfigTag{
+					{Name: "json"},
+				},
+				Getters: []ConfigGetter{
+					{
+						Name:    "Get",
+						Returns: []string{":value"},
+						Output: ConfigGetterOutput{
+							Format:       ConstantFormatPascal,
+							ReceiverType: "UserView",
+						},
+					},
+				},
+			},
+			errorContains: map[string][]string{
+				"getters[0].output.receiver_type": {"Receiver type requires structs_matching, since the methods of every matching struct are declared on the same type"},
+			},
+		},
+		{
+			name: "getter invalid receiver type",
+			config: &Config{
+				Output: ConfigOutput{
+					FileName: "test.go",
+				},
+				Elements: []ConfigTag{
+					{Name: "json"},
+				},
+				Getters: []ConfigGetter{
+					{
+						Name:            "Get",
+						Returns:         []string{":value"},
+						StructsMatching: "^User$",
+						Output: ConfigGetterOutput{
+							Format:       ConstantFormatPascal,
+							ReceiverType: "*UserView",
+						},
+					},
+				},
+			},
+			errorContains: map[string][]string{
+				"getters[0].output.receiver_type": {"\"*UserView\" is not a valid Go identifier"},
+			},
+		},
+		{
+			name: "element aggregate to without constant mode",
+			config: &Config{
+				Output: ConfigOutput{
+					FileName: "test.go",
+				},
+				Elements: []ConfigTag{
+					{
+						Name: "field",
+						Input: ConfigTagInput{
+							Mode:        InputModeTypeField,
+							TagPriority: []string{"json"},
+						},
+						Output: ConfigTagOutput{
+							Mode:        OutputModeStruct,
+							AggregateTo: "keys",
+						},
+					},
+				},
+			},
+			errorContains: map[string][]string{
+				"elements[0].output.aggregate_to": {"Aggregate to requires the constant output mode without typed_key"},
+			},
+		},
+		{
+			name: "element emit values without typed key",
+			config: &Config{
+				Output: ConfigOutput{
+					FileName: "test.go",
+				},
+				Elements: []ConfigTag{
+					{
+						Name: "field",
+						Input: ConfigTagInput{
+							Mode:        InputModeTypeField,
+							TagPriority: []string{"json"},
+						},
+						Output: ConfigTagOutput{
+							Mode:       OutputModeConstant,
+							EmitValues: boolPtr(true),
+						},
+					},
+				},
+			},
+			errorContains: map[string][]string{
+				"elements[0].output.emit_values": {"Emit values requires typed_key"},
+			},
+		},
 		{
 			name: "invalid element tag priority - invalid identifier",
 			config: &Config{
@@ -619,6 +948,30 @@ func TestConfigValidate(t *testing.T) {
 				"elements[0].input.tag_priority[1]": {"\"123invalid\" is not a valid Go identifier"},
 			},
 		},
+		{
+			name: "element keyvalue tag syntax without option key",
+			config: &Config{
+				Output: ConfigOutput{
+					FileName: "test.go",
+				},
+				Input: ConfigInput{
+					Include: []string{"**/*.go"},
+				},
+				Elements: []ConfigTag{
+					{
+						Name: "gorm",
+						Input: ConfigTagInput{
+							Mode:        InputModeTypeTag,
+							TagPriority: []string{"gorm"},
+							TagSyntax:   TagSyntaxKeyValue,
+						},
+					},
+				},
+			},
+			errorContains: map[string][]string{
+				"elements[0].input.option_key": {"Option key can't be blank"},
+			},
+		},
 		{
 			name: "invalid element constant format",
 			config: &Config{
@@ -938,6 +1291,27 @@ func TestConfigValidate(t *testing.T) {
 				"input.field.except":  {"Except must be a valid regular expression"},
 			},
 		},
+		{
+			name: "negative field skip counts",
+			config: &Config{
+				Output: ConfigOutput{
+					FileName: "test.go",
+				},
+				Input: ConfigInput{
+					Include: []string{"**/*.go"},
+					Field: ConfigInputField{
+						Explicit:          boolPtr(false),
+						IncludeUnexported: boolPtr(false),
+						SkipFirst:         -1,
+						SkipLast:          -2,
+					},
+				},
+			},
+			errorContains: map[string][]string{
+				"input.field.skip_first": {"Skip first must be greater than or equal to \"0\""},
+				"input.field.skip_last":  {"Skip last must be greater than or equal to \"0\""},
+			},
+		},
 		{
 			name: "valid config with regex patterns",
 			config: &Config{
@@ -1059,10 +1433,7 @@ func TestConfigValidate(t *testing.T) {
 				Elements: []ConfigTag{
 					{
 						Name: "field",
-						Input: struct {
-							Mode        InputModeType `yaml:"mode"`
-							TagPriority []string      `yaml:"tag_priority"`
-						}{
+						Input: ConfigTagInput{
 							Mode:        InputModeTypeTagThenField,
 							TagPriority: []string{"json", "field"},
 						},
@@ -1225,3 +1596,15 @@ func TestNewConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestDefaultConfig(t *testing.T) {
+	config, err := NewConfig(&Config{})
+	assert.NoError(t, err)
+
+	defaults := DefaultConfig()
+	assert.Equal(t, config, defaults)
+
+	// Each call returns a fresh config
+	defaults.Input.Include[0] = "changed"
+	assert.Equal(t, []string{"**/*.go"}, DefaultConfig().Input.Include)
+}
@@ -0,0 +1,50 @@
+package constago
+
+import "fmt"
+
+// LintReport lists the parts of the config that produced nothing across the
+// whole scan, so they can be pruned
+type LintReport struct {
+	// Elements that didn't produce a value for any field
+	UnusedElements []string
+
+	// Elements with the none output mode that no getter returns, so they
+	// produce no code
+	UnreturnedElements []string
+
+	// Getters whose returns weren't satisfied by any field
+	UnusedGetters []string
+}
+
+// IsEmpty reports whether the lint found nothing to report
+func (r *LintReport) IsEmpty() bool {
+	return len(r.UnusedElements) == 0 && len(r.UnreturnedElements) == 0 && len(r.UnusedGetters) == 0
+}
+
+// Lint builds the model for the config without generating code, and reports
+// the elements and getters that yielded no artifacts
+func Lint(config *Config) (*LintReport, error) {
+	cfg, err := NewConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create config: %w", err)
+	}
+
+	builder := NewModelBuilder(cfg)
+	if _, err := builder.Build(); err != nil {
+		return nil, fmt.Errorf("failed to build model: %w", err)
+	}
+
+	report := &LintReport{}
+	for _, element := range cfg.Elements {
+		if builder.elementValues[element.Name] == 0 {
+			report.UnusedElements = append(report.UnusedElements, element.Name)
+		}
+	}
+	report.UnreturnedElements = builder.unreturnedElements()
+	for _, getter := range cfg.Getters {
+		if builder.getterOutputs[getter.Name] == 0 {
+			report.UnusedGetters = append(report.UnusedGetters, getter.Name)
+		}
+	}
+	return report, nil
+}
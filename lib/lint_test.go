@@ -0,0 +1,125 @@
+package constago
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLint(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type User struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config := &Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "json",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTag,
+					TagPriority: []string{"json"},
+				},
+			},
+			{
+				Name: "xml",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTag,
+					TagPriority: []string{"xml"},
+				},
+			},
+		},
+		Getters: []ConfigGetter{
+			{
+				Name:    "Json",
+				Returns: []string{"json"},
+			},
+			{
+				Name:    "Xml",
+				Returns: []string{"xml", ":value"},
+			},
+		},
+	}
+
+	report, err := Lint(config)
+	require.NoError(t, err)
+
+	assert.False(t, report.IsEmpty())
+	assert.Equal(t, []string{"xml"}, report.UnusedElements)
+	assert.Equal(t, []string{"Xml"}, report.UnusedGetters)
+
+	// Linting doesn't generate code
+	assert.NoFileExists(t, filepath.Join(tempDir, "constago.gen.go"))
+}
+
+func TestLint_UnreturnedElements(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type User struct {
+	Name string ` + "`json:\"name\" title:\"Name\" db:\"name\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config := &Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Elements: []ConfigTag{
+			{Name: "json"},
+			{
+				Name: "title",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTag,
+					TagPriority: []string{"title"},
+				},
+				Output: ConfigTagOutput{Mode: OutputModeNone},
+			},
+			{
+				Name: "db",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTag,
+					TagPriority: []string{"db"},
+				},
+				Output: ConfigTagOutput{Mode: OutputModeNone},
+			},
+		},
+		Getters: []ConfigGetter{
+			{
+				Name:    "Titled",
+				Returns: []string{"title"},
+			},
+		},
+	}
+
+	report, err := Lint(config)
+	require.NoError(t, err)
+
+	// db is neither emitted nor returned by a getter, though it has values
+	assert.False(t, report.IsEmpty())
+	assert.Equal(t, []string{"db"}, report.UnreturnedElements)
+	assert.Empty(t, report.UnusedElements)
+	assert.Empty(t, report.UnusedGetters)
+
+	// Building the model doesn't record it as a scan error
+	cfg, err := NewConfig(config)
+	require.NoError(t, err)
+	model, err := NewModelBuilder(cfg).Build()
+	require.NoError(t, err)
+	assert.Empty(t, model.Errors)
+}
@@ -1,106 +1,398 @@
 package constago
 
-import "fmt"
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"regexp"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ModelSchemaVersion is the version of the dumped model format. It must be
+// bumped whenever a change to the model breaks consumers of the dumps.
+const ModelSchemaVersion = 1
 
 type PackageModel struct {
 	// Package information
-	Name string
-	Path string
+	Name string `json:"name" yaml:"name"`
+	Path string `json:"path" yaml:"path"`
 
 	// Imports to use in the generated code
-	Imports map[string]*TypePackageOutput
+	Imports map[string]*TypePackageOutput `json:"imports" yaml:"imports"`
 
 	// Structs to generate validators for
-	Structs []*StructModel
+	Structs []*StructModel `json:"structs" yaml:"structs"`
+
+	// Map variables whose keys get constants, see input.include_map_vars
+	MapKeys []*MapKeysModel `json:"map_keys" yaml:"map_keys"`
+
+	// Name of the file the package is generated to, only set on the packages
+	// handed to an OutputWriter, telling apart the parts of a package split
+	// by output.split_exported
+	FileName string `json:"file_name,omitempty" yaml:"file_name,omitempty"`
+}
+
+// MapKeysModel holds the key constants of a package-level map variable
+type MapKeysModel struct {
+	Name       string `json:"name" yaml:"name"`
+	File       string `json:"file" yaml:"file"`
+	LineNumber int    `json:"line_number" yaml:"line_number"`
+
+	Constants []*ConstantOutput `json:"constants" yaml:"constants"`
 }
 
 // StructInfo represents a struct that should have code to generate
 type StructModel struct {
-	Name       string
-	File       string
-	LineNumber int
+	Name       string `json:"name" yaml:"name"`
+	File       string `json:"file" yaml:"file"`
+	LineNumber int    `json:"line_number" yaml:"line_number"`
+
+	// Build constraint (//go:build expression) of the declaring file
+	BuildConstraint string `json:"build_constraint" yaml:"build_constraint"`
+
+	// Source location relative to the input dir, only set when
+	// output.emit_source_info is enabled
+	Source string `json:"source" yaml:"source"`
+
+	// Hash of the fields and tags of the struct declaration, only set when
+	// output.emit_source_hash is enabled
+	SourceHash string `json:"source_hash" yaml:"source_hash"`
 
 	// Fields that should have code to generate
-	Constants []*ConstantOutput
-	Structs   []*StructOutput
-	Getters   []*GetterOutput
+	KeyTypes  []*KeyTypeOutput  `json:"key_types" yaml:"key_types"`
+	Constants []*ConstantOutput `json:"constants" yaml:"constants"`
+	Structs   []*StructOutput   `json:"structs" yaml:"structs"`
+	Getters   []*GetterOutput   `json:"getters" yaml:"getters"`
+
+	// Constants of unexported fields, only set when output.split_visibility
+	// is enabled
+	UnexportedConstants []*ConstantOutput `json:"unexported_constants" yaml:"unexported_constants"`
+
+	// Fields producing element values, documented by the markdown format
+	Fields []*FieldModel `json:"fields" yaml:"fields"`
+}
+
+// hasCode reports whether the struct has any Go declaration to generate,
+// which structs only holding values of doc elements don't
+func (s *StructModel) hasCode() bool {
+	return len(s.KeyTypes) > 0 || len(s.Constants) > 0 || len(s.UnexportedConstants) > 0 || len(s.Structs) > 0 || len(s.Getters) > 0
+}
+
+// hasCode reports whether any struct or map variable of the package has Go
+// declarations to generate
+func (pkg *PackageModel) hasCode() bool {
+	if len(pkg.MapKeys) > 0 {
+		return true
+	}
+	for _, s := range pkg.Structs {
+		if s.hasCode() {
+			return true
+		}
+	}
+	return false
+}
+
+// splitExported returns copies of the package holding its exported and its
+// unexported declarations, see output.split_exported. Imports of getter
+// values follow their getters. The other imports, the extra_imports, aren't
+// referenced by any declaration, so they go with the first part having code,
+// which is the exported one unless it's empty.
+func (pkg *PackageModel) splitExported() (*PackageModel, *PackageModel) {
+	exported := &PackageModel{Name: pkg.Name, Path: pkg.Path}
+	internal := &PackageModel{Name: pkg.Name, Path: pkg.Path}
+
+	for _, s := range pkg.Structs {
+		if e := s.withVisibility(true); e.hasCode() {
+			exported.Structs = append(exported.Structs, e)
+		}
+		if i := s.withVisibility(false); i.hasCode() {
+			internal.Structs = append(internal.Structs, i)
+		}
+	}
+	for _, mapKeys := range pkg.MapKeys {
+		e := &MapKeysModel{Name: mapKeys.Name, File: mapKeys.File, LineNumber: mapKeys.LineNumber}
+		i := &MapKeysModel{Name: mapKeys.Name, File: mapKeys.File, LineNumber: mapKeys.LineNumber}
+		e.Constants = constantsWithVisibility(mapKeys.Constants, true)
+		i.Constants = constantsWithVisibility(mapKeys.Constants, false)
+		if len(e.Constants) > 0 {
+			exported.MapKeys = append(exported.MapKeys, e)
+		}
+		if len(i.Constants) > 0 {
+			internal.MapKeys = append(internal.MapKeys, i)
+		}
+	}
+
+	first := exported
+	if !exported.hasCode() {
+		first = internal
+	}
+	getterImports := pkg.getterImports()
+	for _, part := range []*PackageModel{exported, internal} {
+		partImports := part.getterImports()
+		part.Imports = map[string]*TypePackageOutput{}
+		for path, imp := range pkg.Imports {
+			if partImports[path] || (!getterImports[path] && part == first) {
+				part.Imports[path] = imp
+			}
+		}
+	}
+	return exported, internal
+}
+
+// getterImports returns the import paths of the getter values of the package
+func (pkg *PackageModel) getterImports() map[string]bool {
+	paths := map[string]bool{}
+	for _, s := range pkg.Structs {
+		for _, g := range s.Getters {
+			for _, r := range g.Returns {
+				if r.Value != nil && r.Value.TypePackage != nil {
+					paths[r.Value.TypePackage.Path] = true
+				}
+			}
+		}
+	}
+	return paths
+}
+
+// withVisibility returns a copy of the struct with only its exported or only
+// its unexported declarations
+func (s *StructModel) withVisibility(exported bool) *StructModel {
+	part := *s
+	part.KeyTypes = nil
+	part.Structs = nil
+	part.Getters = nil
+	part.Fields = nil
+	for _, keyType := range s.KeyTypes {
+		if ast.IsExported(keyType.Name) == exported {
+			part.KeyTypes = append(part.KeyTypes, keyType)
+		}
+	}
+	part.Constants = constantsWithVisibility(s.Constants, exported)
+	part.UnexportedConstants = constantsWithVisibility(s.UnexportedConstants, exported)
+	for _, so := range s.Structs {
+		if ast.IsExported(so.Name) == exported {
+			part.Structs = append(part.Structs, so)
+		}
+	}
+	for _, g := range s.Getters {
+		if ast.IsExported(g.Name) == exported {
+			part.Getters = append(part.Getters, g)
+		}
+	}
+	return &part
+}
+
+// constantsWithVisibility returns copies of the exported or the unexported
+// constants, moving the start of a group to its first kept constant
+func constantsWithVisibility(constants []*ConstantOutput, exported bool) []*ConstantOutput {
+	var kept []*ConstantOutput
+	pendingGroup, pending := "", false
+	for _, c := range constants {
+		if c.GroupStart {
+			pendingGroup, pending = c.Group, true
+		}
+		if ast.IsExported(c.Name) != exported {
+			continue
+		}
+		k := *c
+		k.GroupStart = pending && c.Group == pendingGroup
+		if k.GroupStart {
+			pending = false
+		}
+		kept = append(kept, &k)
+	}
+	return kept
+}
+
+// FieldModel holds the values produced for a struct field by element name
+type FieldModel struct {
+	Name   string            `json:"name" yaml:"name"`
+	Values map[string]string `json:"values" yaml:"values"`
 }
 
 type ScanError struct {
-	File    string
-	Line    int
-	Message string
+	File    string `json:"file" yaml:"file"`
+	Line    int    `json:"line" yaml:"line"`
+	Message string `json:"message" yaml:"message"`
 }
 
 type StructOutput struct {
-	Name    string
-	Package string
+	Name    string `json:"name" yaml:"name"`
+	Package string `json:"package" yaml:"package"`
 
-	Fields []*FieldOutput
+	Fields []*FieldOutput `json:"fields" yaml:"fields"`
 }
 
 type ConstantOutput struct {
-	Name  string
-	Value string
+	Name  string `json:"name" yaml:"name"`
+	Type  string `json:"type" yaml:"type"`
+	Value string `json:"value" yaml:"value"`
+
+	// Literal writes the value as is instead of quoting it, e.g. for numbers
+	Literal bool `json:"literal" yaml:"literal"`
+
+	// Group is the category of the field, see output.group_by_tag, and
+	// GroupStart marks the first constant of the group
+	Group      string `json:"group" yaml:"group"`
+	GroupStart bool   `json:"group_start" yaml:"group_start"`
+
+	// Comment is written after the constant, holding the tag options with
+	// output.annotate_options
+	Comment string `json:"comment" yaml:"comment"`
+}
+
+// KeyTypeOutput is a named string type used by typed key constants
+type KeyTypeOutput struct {
+	Name string `json:"name" yaml:"name"`
+
+	// Values are the names of the constants listed by the Values method,
+	// see the element output.emit_values
+	Values []string `json:"values" yaml:"values"`
 }
 
 type FieldOutput struct {
-	StructName string
-	Name       string
-	Value      string
+	StructName string `json:"struct_name" yaml:"struct_name"`
+	Name       string `json:"name" yaml:"name"`
+	Value      string `json:"value" yaml:"value"`
+
+	// Tag of the holder field, copied from the source field when
+	// output.struct_field_tags is copy
+	Tag string `json:"tag" yaml:"tag"`
 }
 
 type NoneOutput struct {
-	Name  string
-	Value string
+	Name  string `json:"name" yaml:"name"`
+	Value string `json:"value" yaml:"value"`
 }
 
 type ValueOutput struct {
-	FieldName   string
-	TypeName    string
-	TypePackage *TypePackageOutput
+	FieldName   string             `json:"field_name" yaml:"field_name"`
+	TypeName    string             `json:"type_name" yaml:"type_name"`
+	TypePackage *TypePackageOutput `json:"type_package" yaml:"type_package"`
+
+	// Package qualifier used by TypeName, e.g. binary for binary.Number,
+	// rewritten by Finalize when the import gets another alias
+	Qualifier string `json:"qualifier" yaml:"qualifier"`
+}
+
+// isUnresolved reports whether the type is qualified by a package that
+// couldn't be mapped to an import path
+func (v *ValueOutput) isUnresolved(packageName string) bool {
+	return v.TypePackage != nil && v.TypePackage.Path == "" && v.TypePackage.Name != packageName
 }
 
 type TypePackageOutput struct {
-	Path  string
-	Name  string
-	Alias string
+	Path  string `json:"path" yaml:"path"`
+	Name  string `json:"name" yaml:"name"`
+	Alias string `json:"alias" yaml:"alias"`
 }
 
 type ReturnOutput struct {
-	Field    *FieldOutput
-	Constant *ConstantOutput
-	None     *NoneOutput
-	Value    *ValueOutput
+	Field    *FieldOutput    `json:"field" yaml:"field"`
+	Constant *ConstantOutput `json:"constant" yaml:"constant"`
+	None     *NoneOutput     `json:"none" yaml:"none"`
+	Value    *ValueOutput    `json:"value" yaml:"value"`
+
+	// Reference returns the constant by name instead of inlining its value
+	Reference bool `json:"reference" yaml:"reference"`
+
+	// ResultName names the result parameter in the getter signature
+	ResultName string `json:"result_name" yaml:"result_name"`
 }
 
 type GetterOutput struct {
-	Name    string
-	Returns []*ReturnOutput
+	Name    string          `json:"name" yaml:"name"`
+	Returns []*ReturnOutput `json:"returns" yaml:"returns"`
+
+	// ErrorExpr is the expression of the error appended to the results, only
+	// set when the getter output.error_return is enabled
+	ErrorExpr string `json:"error_expr" yaml:"error_expr"`
+
+	// Receiver is the type the method is declared on, the struct when empty
+	Receiver string `json:"receiver" yaml:"receiver"`
 }
 
 type Model struct {
+	// Version of the dumped model format, see ModelSchemaVersion
+	SchemaVersion int `json:"schema_version" yaml:"schema_version"`
+
 	// Packages organized by path
-	Packages map[string]*PackageModel
+	Packages map[string]*PackageModel `json:"packages" yaml:"packages"`
 
 	// Scanning statistics
-	FilesScanned  int
-	PackagesFound int
-	StructsFound  int
-	FieldsFound   int
+	FilesScanned int `json:"files_scanned" yaml:"files_scanned"`
+	// Files skipped without parsing by input.fast_skip or input.require_module
+	FilesSkipped  int `json:"files_skipped" yaml:"files_skipped"`
+	PackagesFound int `json:"packages_found" yaml:"packages_found"`
+	StructsFound  int `json:"structs_found" yaml:"structs_found"`
+	FieldsFound   int `json:"fields_found" yaml:"fields_found"`
 
 	// Errors encountered during scanning
-	Errors []*ScanError
+	Errors []*ScanError `json:"errors" yaml:"errors"`
+
+	// Imports added to every package, see output.extra_imports
+	extraImports []string
 }
 
 func NewModel(config *Config) *Model {
-	return &Model{
-		Packages: make(map[string]*PackageModel),
+	model := &Model{
+		SchemaVersion: ModelSchemaVersion,
+		Packages:      make(map[string]*PackageModel),
+	}
+	if config != nil {
+		model.extraImports = config.Output.ExtraImports
+	}
+	return model
+}
+
+// DumpJSON returns the model encoded as indented JSON
+func (m *Model) DumpJSON() ([]byte, error) {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to dump model as JSON: %w", err)
+	}
+	return data, nil
+}
+
+// DumpYAML returns the model encoded as YAML
+func (m *Model) DumpYAML() ([]byte, error) {
+	data, err := yaml.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dump model as YAML: %w", err)
 	}
+	return data, nil
 }
 
+// AddStruct appends a struct to its package and registers the imports of
+// its getters. Only emitted getters must be set in the struct, so getters
+// dropped for unsatisfied returns don't leave unused imports behind.
 func (m *Model) AddStruct(packagePath string, packageName string, structModel *StructModel) {
 
+	pkg := m.packageModel(packagePath, packageName)
+
+	for _, g := range structModel.Getters {
+		for _, r := range g.Returns {
+			if r.Value != nil && r.Value.TypePackage != nil {
+				pkg.AddImport(r.Value.TypePackage)
+			}
+		}
+	}
+
+	pkg.Structs = append(pkg.Structs, structModel)
+
+	m.StructsFound++
+}
+
+// AddMapKeys appends the key constants of a map variable to its package
+func (m *Model) AddMapKeys(packagePath string, packageName string, mapKeys *MapKeysModel) {
+	pkg := m.packageModel(packagePath, packageName)
+	pkg.MapKeys = append(pkg.MapKeys, mapKeys)
+}
+
+// packageModel returns the package at the path, adding it when missing
+func (m *Model) packageModel(packagePath string, packageName string) *PackageModel {
 	pkg := m.Packages[packagePath]
 
 	// Initialize package if it doesn't exist
@@ -113,33 +405,114 @@ func (m *Model) AddStruct(packagePath string, packageName string, structModel *S
 		}
 		m.Packages[packagePath] = pkg
 		m.PackagesFound++
+
+		for _, entry := range m.extraImports {
+			pkg.AddImport(parseImport(entry))
+		}
 	}
+	return pkg
+}
 
-	var setRecursiveAlias func(pkg *PackageModel, currentImport *TypePackageOutput, currentNameOrAlias string, level int)
-	setRecursiveAlias = func(pkg *PackageModel, currentImport *TypePackageOutput, currentNameOrAlias string, level int) {
-		for _, imp := range pkg.Imports {
-			if imp.Path != currentImport.Path &&
-				(imp.Name == currentNameOrAlias || imp.Alias == currentNameOrAlias) {
-				currentImport.Alias = fmt.Sprintf("_%s", currentNameOrAlias)
-				setRecursiveAlias(pkg, currentImport, currentImport.Alias, level+1)
+// AddImport registers an import in the package unless its path is already
+// imported. Aliases for colliding names are assigned by Finalize.
+func (pkg *PackageModel) AddImport(imp *TypePackageOutput) {
+	if _, exists := pkg.Imports[imp.Path]; exists {
+		return
+	}
+	pkg.Imports[imp.Path] = imp
+}
+
+// Finalize assigns the aliases of colliding imports in every package. Extra
+// imports are processed first and the discovered ones by path, so the
+// aliases don't depend on the order the structs were added. Aliases taken
+// from the source files are kept unless they collide. It must be called once
+// all the structs are added, and it's safe to call it again.
+func (m *Model) Finalize() {
+	extraPaths := map[string]bool{}
+	for _, entry := range m.extraImports {
+		extraPaths[parseImport(entry).Path] = true
+	}
+
+	for _, pkg := range m.Packages {
+		paths := make([]string, 0, len(pkg.Imports))
+		for path := range pkg.Imports {
+			paths = append(paths, path)
+		}
+		sort.Slice(paths, func(i, j int) bool {
+			if extraPaths[paths[i]] != extraPaths[paths[j]] {
+				return extraPaths[paths[i]]
+			}
+			return paths[i] < paths[j]
+		})
+
+		taken := map[string]bool{}
+		for _, path := range paths {
+			imp := pkg.Imports[path]
+			// Blank and dot imports can't collide
+			if imp.Name == "_" || imp.Name == "." {
+				continue
+			}
+			nameOrAlias := imp.Name
+			if imp.Alias != "" {
+				nameOrAlias = imp.Alias
 			}
+			for taken[nameOrAlias] {
+				nameOrAlias = fmt.Sprintf("_%s", nameOrAlias)
+			}
+			imp.Alias = ""
+			if nameOrAlias != imp.Name {
+				imp.Alias = nameOrAlias
+			}
+			taken[nameOrAlias] = true
 		}
+
+		pkg.requalifyValues()
 	}
+}
 
-	for _, g := range structModel.Getters {
-		for _, r := range g.Returns {
-			if r.Value != nil {
-				if _, exists := pkg.Imports[r.Value.TypePackage.Path]; !exists {
-					pkg.Imports[r.Value.TypePackage.Path] = r.Value.TypePackage
-					setRecursiveAlias(pkg, r.Value.TypePackage, r.Value.TypePackage.Name, 0)
+// requalifyValues points the getter values to the imports of the package, so
+// their types are qualified by the final alias, e.g. _binary.Number when the
+// binary alias of the source collides with another import
+func (pkg *PackageModel) requalifyValues() {
+	for _, s := range pkg.Structs {
+		for _, g := range s.Getters {
+			for _, r := range g.Returns {
+				v := r.Value
+				if v == nil || v.TypePackage == nil || v.TypePackage.Path == "" {
+					continue
+				}
+				imp, ok := pkg.Imports[v.TypePackage.Path]
+				if !ok || imp.Name == "_" || imp.Name == "." {
+					continue
+				}
+				v.TypePackage = imp
+				if v.Qualifier == "" {
+					v.Qualifier = typeQualifier(v.TypeName)
+				}
+				qualifier := imp.Name
+				if imp.Alias != "" {
+					qualifier = imp.Alias
+				}
+				if v.Qualifier != "" && v.Qualifier != qualifier {
+					old := regexp.MustCompile(`\b` + regexp.QuoteMeta(v.Qualifier) + `\.`)
+					v.TypeName = old.ReplaceAllLiteralString(v.TypeName, qualifier+".")
+					v.Qualifier = qualifier
 				}
 			}
 		}
 	}
+}
 
-	pkg.Structs = append(pkg.Structs, structModel)
+// typeQualifierRegexp matches the first package qualifier of a type name
+var typeQualifierRegexp = regexp.MustCompile(`([A-Za-z_][A-Za-z0-9_]*)\.[A-Za-z_]`)
 
-	m.StructsFound++
+// typeQualifier returns the package qualifier of a type name, e.g. binary
+// for []*binary.Number, or an empty string for unqualified types
+func typeQualifier(typeName string) string {
+	if m := typeQualifierRegexp.FindStringSubmatch(typeName); m != nil {
+		return m[1]
+	}
+	return ""
 }
 
 // AddError appends a scanning error to the model
@@ -1,17 +1,28 @@
 package constago
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"go/ast"
+	"go/build/constraint"
 	"go/parser"
 	goScanner "go/scanner"
 	"go/token"
+	"go/types"
+	"maps"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"reflect"
 	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"text/template"
 
 	"github.com/bmatcuk/doublestar/v4"
 )
@@ -20,6 +31,23 @@ import (
 type modelBuilder struct {
 	config *Config
 	model  *Model
+
+	// Usage counters by element and getter name, used to lint the config
+	elementValues map[string]int
+	getterOutputs map[string]int
+
+	// resolveCache persists resolved package names, nil when disabled
+	resolveCache *packageNameCache
+
+	// Elements of the constants moved by output.aggregate_to
+	aggregatedConstants map[*ConstantOutput]*ConfigTag
+
+	// Types declared by the package of each directory, by name
+	packageTypes map[string]map[string]ast.Expr
+
+	// Names of the structs to generate with input.include_referenced, by
+	// package directory, decided over all the scanned files of the package
+	referencedStructs map[string]map[string]bool
 }
 
 // BuildModel builds and returns a populated Model for the given config
@@ -30,11 +58,284 @@ func (b *modelBuilder) Build() (*Model, error) {
 		return nil, err
 	}
 
+	b.dropGeneratedHolders()
+
+	if err := b.checkDuplicateStructs(); err != nil {
+		return nil, err
+	}
+
+	if err := b.checkStrippedStructNames(); err != nil {
+		return nil, err
+	}
+
+	if err := b.checkReceiverTypes(); err != nil {
+		return nil, err
+	}
+
+	if err := b.aggregateConstants(); err != nil {
+		return nil, err
+	}
+
+	if err := b.dedupConstants(); err != nil {
+		return nil, err
+	}
+
+	if caseType := b.config.Output.IdentifierCase; caseType != "" && caseType != IdentifierCaseNone {
+		if err := b.applyIdentifierCase(caseType); err != nil {
+			return nil, err
+		}
+	}
+
+	if b.config.Output.MaxIdentifierLength > 0 {
+		if err := b.applyMaxIdentifierLength(b.config.Output.MaxIdentifierLength); err != nil {
+			return nil, err
+		}
+	}
+
+	if b.resolveCache != nil {
+		if err := b.resolveCache.save(); err != nil {
+			return nil, err
+		}
+	}
+
+	b.model.Finalize()
+
 	return b.model, nil
 }
 
+// unreturnedElements returns the names of the elements with the none output
+// mode that no getter returns, since they produce nothing
+func (b *modelBuilder) unreturnedElements() []string {
+	returned := map[string]bool{}
+	for _, getter := range b.config.Getters {
+		for _, name := range getter.Returns {
+			returned[name] = true
+		}
+	}
+	var names []string
+	for _, el := range b.config.Elements {
+		if el.Output.Mode == OutputModeNone && !returned[el.Name] {
+			names = append(names, el.Name)
+		}
+	}
+	return names
+}
+
+// applyIdentifierCase reformats the identifiers of the whole model with
+// output.identifier_case. Unexported identifiers stay unexported, and the
+// names referring to reformatted ones, like key types and Values lists,
+// follow them. An error is returned when two identifiers of the same scope
+// end up with the same name, e.g. JsonUserName and JSONUserName.
+func (b *modelBuilder) applyIdentifierCase(caseType ConstantFormatType) error {
+	format := func(name string) string {
+		formatted := b.buildName("", name, "", "", caseType, false)
+		if !ast.IsExported(name) {
+			formatted = unexportName(formatted)
+		}
+		return formatted
+	}
+
+	paths := make([]string, 0, len(b.model.Packages))
+	for path := range b.model.Packages {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		pkg := b.model.Packages[path]
+		// Original identifiers by scope and formatted name
+		originals := map[string]map[string]string{}
+		var collision error
+		declare := func(scope string, name string, formatted string) {
+			if _, ok := originals[scope]; !ok {
+				originals[scope] = map[string]string{}
+			}
+			if original, ok := originals[scope][formatted]; ok && original != name && collision == nil {
+				collision = fmt.Errorf("identifiers %s and %s in %s are both formatted as %s (output.identifier_case)", original, name, path, formatted)
+			}
+			originals[scope][formatted] = name
+		}
+
+		for _, structModel := range pkg.Structs {
+			renamed := map[string]string{}
+			rename := func(name string) string {
+				if formatted, ok := renamed[name]; ok {
+					return formatted
+				}
+				formatted := format(name)
+				renamed[name] = formatted
+				return formatted
+			}
+
+			for _, keyType := range structModel.KeyTypes {
+				name := keyType.Name
+				keyType.Name = rename(name)
+				declare("", name, keyType.Name)
+			}
+			for _, constants := range [][]*ConstantOutput{structModel.Constants, structModel.UnexportedConstants} {
+				for _, c := range constants {
+					name := c.Name
+					c.Name = rename(name)
+					declare("", name, c.Name)
+					if c.Type != "" {
+						c.Type = rename(c.Type)
+					}
+				}
+			}
+			for _, keyType := range structModel.KeyTypes {
+				for i, value := range keyType.Values {
+					keyType.Values[i] = rename(value)
+				}
+			}
+			for _, so := range structModel.Structs {
+				name := so.Name
+				so.Name = rename(name)
+				declare("", name, so.Name)
+				for _, field := range so.Fields {
+					fieldName := field.Name
+					field.StructName = so.Name
+					field.Name = format(fieldName)
+					declare(so.Name, fieldName, field.Name)
+				}
+			}
+			for _, getter := range structModel.Getters {
+				name := getter.Name
+				getter.Name = format(name)
+				receiver := getter.Receiver
+				if receiver == "" {
+					receiver = structModel.Name
+				}
+				declare(receiver, name, getter.Name)
+			}
+		}
+		for _, mapKeys := range pkg.MapKeys {
+			for _, c := range mapKeys.Constants {
+				name := c.Name
+				c.Name = format(name)
+				declare("", name, c.Name)
+			}
+		}
+		if collision != nil {
+			return collision
+		}
+	}
+	return nil
+}
+
+// applyMaxIdentifierLength truncates the identifiers of the whole model longer
+// than output.max_identifier_length, see truncateIdentifier. The names
+// referring to truncated ones follow them, and an error is returned when two
+// identifiers of the same scope end up with the same name.
+func (b *modelBuilder) applyMaxIdentifierLength(maxLength int) error {
+	paths := make([]string, 0, len(b.model.Packages))
+	for path := range b.model.Packages {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		pkg := b.model.Packages[path]
+		// Original identifiers by scope and truncated name
+		originals := map[string]map[string]string{}
+		var collision error
+		truncate := func(scope string, name string) string {
+			truncated := truncateIdentifier(name, maxLength)
+			if _, ok := originals[scope]; !ok {
+				originals[scope] = map[string]string{}
+			}
+			if original, ok := originals[scope][truncated]; ok && original != name && collision == nil {
+				collision = fmt.Errorf("identifiers %s and %s in %s are both truncated to %s (output.max_identifier_length)", original, name, path, truncated)
+			}
+			originals[scope][truncated] = name
+			return truncated
+		}
+
+		for _, structModel := range pkg.Structs {
+			for _, keyType := range structModel.KeyTypes {
+				keyType.Name = truncate("", keyType.Name)
+				for i, value := range keyType.Values {
+					keyType.Values[i] = truncateIdentifier(value, maxLength)
+				}
+			}
+			for _, constants := range [][]*ConstantOutput{structModel.Constants, structModel.UnexportedConstants} {
+				for _, c := range constants {
+					c.Name = truncate("", c.Name)
+					c.Type = truncateIdentifier(c.Type, maxLength)
+				}
+			}
+			for _, so := range structModel.Structs {
+				so.Name = truncate("", so.Name)
+				for _, field := range so.Fields {
+					field.StructName = so.Name
+					field.Name = truncate(so.Name, field.Name)
+				}
+			}
+			for _, getter := range structModel.Getters {
+				getter.Name = truncate(structModel.Name, getter.Name)
+			}
+		}
+		for _, mapKeys := range pkg.MapKeys {
+			for _, c := range mapKeys.Constants {
+				c.Name = truncate("", c.Name)
+			}
+		}
+		if collision != nil {
+			return collision
+		}
+	}
+	return nil
+}
+
+// truncateIdentifier shortens an identifier longer than maxLength runes,
+// replacing its end with the first characters of the hex SHA-256 of the full
+// name. The first rune is kept, so exported identifiers stay exported.
+func truncateIdentifier(name string, maxLength int) string {
+	runes := []rune(name)
+	if len(runes) <= maxLength {
+		return name
+	}
+	const hashLength = 8
+	sum := sha256.Sum256([]byte(name))
+	return string(runes[:maxLength-hashLength]) + hex.EncodeToString(sum[:])[:hashLength]
+}
+
+// renderErrorExpr executes the error_expr template of a getter for a field,
+// returning nil when it isn't set
+func renderErrorExpr(g *ConfigGetter, structName string, fieldName string) (string, error) {
+	if isStringBlank(g.Output.ErrorExpr) {
+		return "nil", nil
+	}
+	tmpl, err := template.New("error_expr").Parse(g.Output.ErrorExpr)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse error expression of getter %s: %w", g.Name, err)
+	}
+	var expr bytes.Buffer
+	data := struct {
+		Receiver string
+		Struct   string
+		Field    string
+	}{
+		Receiver: "_struct",
+		Struct:   structName,
+		Field:    fieldName,
+	}
+	if err := tmpl.Execute(&expr, data); err != nil {
+		return "", fmt.Errorf("failed to execute error expression of getter %s: %w", g.Name, err)
+	}
+	return strings.TrimSpace(expr.String()), nil
+}
+
 func NewModelBuilder(config *Config) *modelBuilder {
-	return &modelBuilder{config: config, model: NewModel(config)}
+	b := &modelBuilder{
+		config:        config,
+		model:         NewModel(config),
+		elementValues: map[string]int{},
+		getterOutputs: map[string]int{},
+	}
+	if !isStringBlank(config.Input.ResolveCache) {
+		b.resolveCache = newPackageNameCache(config.Input.ResolveCache)
+	}
+	return b
 }
 
 // findFiles resolves include/exclude patterns into a set of Go files
@@ -61,7 +362,8 @@ func (b *modelBuilder) findFiles() ([]string, error) {
 			return nil, fmt.Errorf("failed to expand pattern %s: %w", include, err)
 		}
 		for _, p := range paths {
-			if !mustExclude[p] {
+			// Generated files are never scanned again
+			if !mustExclude[p] && !config.Output.isGeneratedFile(p) {
 				includeSet[p] = true
 			}
 		}
@@ -71,6 +373,8 @@ func (b *modelBuilder) findFiles() ([]string, error) {
 	for p := range includeSet {
 		files = append(files, p)
 	}
+	// Sort to scan files, and so emit structs, in a deterministic order
+	sort.Strings(files)
 	return files, nil
 }
 
@@ -81,6 +385,10 @@ func (b *modelBuilder) scanFiles() error {
 		return err
 	}
 
+	if b.config.Input.isIncludeReferenced() {
+		b.collectReferencedStructs(files)
+	}
+
 	for _, file := range files {
 		if err := b.scanFile(file); err != nil {
 			return err
@@ -134,7 +442,7 @@ func (b *modelBuilder) findPackageFiles(packageName string) ([]string, error) {
 			// best effort; skip invalid files
 			return nil
 		}
-		if node != nil && node.Name.Name == packageName {
+		if node != nil && node.Name != nil && node.Name.Name == packageName {
 			files = append(files, path)
 		}
 		return nil
@@ -180,18 +488,228 @@ func (s *modelBuilder) mustIncludeStruct(genDecl *ast.GenDecl, typeSpec *ast.Typ
 		}
 	}
 
+	// Check require_field_tag, at least one field must carry the tag
+	if tag := strings.TrimSpace(s.config.Input.Struct.RequireFieldTag); tag != "" {
+		structType, ok := typeSpec.Type.(*ast.StructType)
+		if !ok || !s.hasFieldTag(structType, tag) {
+			return false
+		}
+	}
+
 	return true
 }
 
+// includedStructs returns the struct declarations of the file to generate:
+// the ones passing the struct rules plus, with input.include_referenced, the
+// structs of the package their fields refer to, e.g. Item for Items []Item or
+// map[string]*Item, followed recursively. Structs with the exclude directive
+// are never pulled in.
+func (b *modelBuilder) includedStructs(node *ast.File, fset *token.FileSet, filePath string) map[*ast.TypeSpec]bool {
+	included := map[*ast.TypeSpec]bool{}
+	referable := map[string]*ast.TypeSpec{}
+	var roots []*ast.TypeSpec
+
+	b.eachStructSpec(node, func(genDecl *ast.GenDecl, typeSpec *ast.TypeSpec) {
+		if _, exclude := b.structDirectives(genDecl, typeSpec); !exclude {
+			referable[typeSpec.Name.Name] = typeSpec
+		}
+		if b.mustIncludeStruct(genDecl, typeSpec, fset, filePath) {
+			included[typeSpec] = true
+			roots = append(roots, typeSpec)
+		}
+	})
+
+	if !b.config.Input.isIncludeReferenced() {
+		return included
+	}
+	// The references across the files of the package are collected before
+	// the scan, files scanned alone only follow their own
+	if names, ok := b.referencedStructs[packageKey(filePath, node)]; ok {
+		for name, typeSpec := range referable {
+			if names[name] {
+				included[typeSpec] = true
+			}
+		}
+		return included
+	}
+	for typeSpec := range referencedClosure(referable, roots) {
+		included[typeSpec] = true
+	}
+	return included
+}
+
+// collectReferencedStructs records the structs of every package to generate
+// with input.include_referenced, following the references across all the
+// scanned files of the package, e.g. Item declared in item.go for Items
+// []Item in order.go
+func (b *modelBuilder) collectReferencedStructs(files []string) {
+	referable := map[string]map[string]*ast.TypeSpec{}
+	roots := map[string][]*ast.TypeSpec{}
+
+	for _, filePath := range files {
+		if b.config.Input.isRequireModule() {
+			if moduleDir, _ := locateGoModule(filePath); moduleDir == "" {
+				continue
+			}
+		}
+		fset := token.NewFileSet()
+		node, err := parser.ParseFile(fset, filePath, nil, parser.ParseComments|parser.SkipObjectResolution)
+		// Invalid and generated files are reported or skipped by the scan
+		if err != nil || node.Name == nil || isConstagoGenerated(node) {
+			continue
+		}
+		key := packageKey(filePath, node)
+		if referable[key] == nil {
+			referable[key] = map[string]*ast.TypeSpec{}
+		}
+		b.eachStructSpec(node, func(genDecl *ast.GenDecl, typeSpec *ast.TypeSpec) {
+			include, exclude := b.structDirectives(genDecl, typeSpec)
+			if !exclude {
+				referable[key][typeSpec.Name.Name] = typeSpec
+			}
+			// Structs with both directives are reported by the scan
+			if !(include && exclude) && b.mustIncludeStruct(genDecl, typeSpec, fset, filePath) {
+				roots[key] = append(roots[key], typeSpec)
+			}
+		})
+	}
+
+	b.referencedStructs = map[string]map[string]bool{}
+	for key, structs := range referable {
+		names := map[string]bool{}
+		for typeSpec := range referencedClosure(structs, roots[key]) {
+			names[typeSpec.Name.Name] = true
+		}
+		b.referencedStructs[key] = names
+	}
+}
+
+// packageKey identifies the package of a file by its directory and name
+func packageKey(filePath string, node *ast.File) string {
+	return filepath.Dir(filePath) + ":" + node.Name.Name
+}
+
+// eachStructSpec calls fn for every struct declaration of the file, skipping
+// the anonymous ones unless input.struct.include_anonymous is enabled
+func (b *modelBuilder) eachStructSpec(node *ast.File, fn func(genDecl *ast.GenDecl, typeSpec *ast.TypeSpec)) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		genDecl, ok := n.(*ast.GenDecl)
+		if !ok || genDecl.Tok != token.TYPE {
+			return true
+		}
+		for _, spec := range genDecl.Specs {
+			typeSpec, ok := spec.(*ast.TypeSpec)
+			if !ok {
+				continue
+			}
+			if _, ok := typeSpec.Type.(*ast.StructType); !ok {
+				continue
+			}
+			if typeSpec.Assign.IsValid() && !b.config.Input.Struct.isIncludeAnonymous() {
+				continue
+			}
+			fn(genDecl, typeSpec)
+		}
+		return true
+	})
+}
+
+// referencedClosure returns the roots and the referable structs their fields
+// refer to, followed recursively
+func referencedClosure(referable map[string]*ast.TypeSpec, roots []*ast.TypeSpec) map[*ast.TypeSpec]bool {
+	included := map[*ast.TypeSpec]bool{}
+	queue := slices.Clone(roots)
+	for _, typeSpec := range roots {
+		included[typeSpec] = true
+	}
+	for len(queue) > 0 {
+		typeSpec := queue[0]
+		queue = queue[1:]
+		for _, field := range typeSpec.Type.(*ast.StructType).Fields.List {
+			for _, name := range referencedTypeNames(field.Type) {
+				if referenced, ok := referable[name]; ok && !included[referenced] {
+					included[referenced] = true
+					queue = append(queue, referenced)
+				}
+			}
+		}
+	}
+	return included
+}
+
+// referencedTypeNames returns the names of the types of the package used by
+// a type expression, e.g. Item and Tag for map[Tag][]*Item
+func referencedTypeNames(expr ast.Expr) []string {
+	var names []string
+	ast.Inspect(expr, func(n ast.Node) bool {
+		switch t := n.(type) {
+		case *ast.SelectorExpr:
+			// Types of other packages
+			return false
+		case *ast.Ident:
+			names = append(names, t.Name)
+		}
+		return true
+	})
+	return names
+}
+
+// hasFieldTag reports whether any field of the struct carries the tag
+func (s *modelBuilder) hasFieldTag(structType *ast.StructType, tag string) bool {
+	for _, field := range structType.Fields.List {
+		if _, ok := s.lookupTag(parseStructTags(fieldTagText(field)), tag); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// structModeDirective returns the output mode set by a //constago:mode=MODE
+// directive of the struct, overriding the mode of every element, or "" when
+// missing. Invalid modes are reported and ignored.
+func (s *modelBuilder) structModeDirective(genDecl *ast.GenDecl, typeSpec *ast.TypeSpec, fset *token.FileSet, filePath string) OutputModeType {
+	for _, cg := range []*ast.CommentGroup{genDecl.Doc, typeSpec.Doc} {
+		if cg == nil {
+			continue
+		}
+		for _, c := range cg.List {
+			txt := strings.TrimSpace(strings.TrimPrefix(c.Text, "//"))
+			value, ok := strings.CutPrefix(txt, "constago:mode=")
+			if !ok {
+				continue
+			}
+			mode := OutputModeType(strings.TrimSpace(value))
+			if !slices.Contains(validOutputModes, mode) {
+				modes := make([]string, len(validOutputModes))
+				for i, m := range validOutputModes {
+					modes[i] = string(m)
+				}
+				s.model.AddError(filePath, fset.Position(c.Pos()).Line, fmt.Sprintf("invalid //constago:mode directive %q, must be %s", mode, strings.Join(modes, ", ")))
+				return ""
+			}
+			return mode
+		}
+	}
+	return ""
+}
+
 // structDirectives inspects comments attached to a type declaration/spec
 // and returns whether include/exclude directives are present.
 func (s *modelBuilder) structDirectives(genDecl *ast.GenDecl, typeSpec *ast.TypeSpec) (bool, bool) {
+	// Comments may be on the declaration or on the specific spec/name doc,
+	// if the TypeSpec has its own doc/comments (rare but possible)
+	return commentDirectives(genDecl.Doc, typeSpec.Doc)
+}
+
+// commentDirectives returns whether the include/exclude directives are
+// present in the comment groups
+func commentDirectives(groups ...*ast.CommentGroup) (bool, bool) {
 	hasInclude := false
 	hasExclude := false
 
-	checkCommentGroup := func(cg *ast.CommentGroup) {
+	for _, cg := range groups {
 		if cg == nil {
-			return
+			continue
 		}
 		for _, c := range cg.List {
 			txt := strings.TrimSpace(c.Text)
@@ -205,11 +723,6 @@ func (s *modelBuilder) structDirectives(genDecl *ast.GenDecl, typeSpec *ast.Type
 		}
 	}
 
-	// Comments may be on the declaration or on the specific spec/name doc
-	checkCommentGroup(genDecl.Doc)
-	// If the TypeSpec has its own doc/comments (rare but possible), check those
-	checkCommentGroup(typeSpec.Doc)
-
 	return hasInclude, hasExclude
 }
 
@@ -217,8 +730,33 @@ func (b *modelBuilder) scanFile(filePath string) error {
 
 	b.model.FilesScanned++
 
+	if b.config.Input.isRequireModule() {
+		if moduleDir, _ := locateGoModule(filePath); moduleDir == "" {
+			b.model.FilesSkipped++
+			return nil
+		}
+	}
+
+	// Source already read by the fast skip, nil lets the parser read the file
+	var src any
+	if b.config.Input.isFastSkip() {
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", filePath, err)
+		}
+		// A file declaring any struct contains the keyword, so files without
+		// it can't produce output and aren't parsed, unless they may declare
+		// map variables
+		hasMapVars := b.config.Input.isIncludeMapVars() && bytes.Contains(content, []byte("map["))
+		if !bytes.Contains(content, []byte("struct")) && !hasMapVars {
+			b.model.FilesSkipped++
+			return nil
+		}
+		src = content
+	}
+
 	fset := token.NewFileSet()
-	node, err := parser.ParseFile(fset, filePath, nil, parser.ParseComments)
+	node, err := parser.ParseFile(fset, filePath, src, parser.ParseComments)
 	if err != nil {
 		// attach parsing error with line when available
 		line := 0
@@ -230,15 +768,45 @@ func (b *modelBuilder) scanFile(filePath string) error {
 		b.model.AddError(filePath, line, fmt.Sprintf("failed to parse file: %v", err))
 		return nil
 	}
+	// Files produced by a previous run, even under another file name, only
+	// hold generated holders and constants that must not be scanned again
+	if isConstagoGenerated(node) {
+		return nil
+	}
 
 	packagePath := b.extractPackagePath(filePath)
 	packageName := node.Name.Name
+	buildConstraint := fileBuildConstraint(node)
 	// Build import index for resolving selector types to full import info
-	importIndex, modulePath := b.buildImportIndex(node, filePath)
+	importIndex, modulePath, err := b.buildImportIndex(node, filePath)
+	if err != nil {
+		return err
+	}
 	moduleDir, _ := locateGoModule(filePath)
 
+	// Errors that must stop the scan, reported once the inspection ends
+	var scanErr error
+
+	// Types declared in the file, resolving the embedded structs to flatten
+	var localTypes map[string]ast.Expr
+	if b.config.Input.Field.isFlattenEmbedded() {
+		localTypes = fileTypeDecls(node)
+	}
+	// Types declared in the whole package, resolving the JSON types of named
+	// fields declared in other files
+	var packageTypes map[string]ast.Expr
+	if b.config.Output.isEmitJSONType() {
+		packageTypes = b.packageTypeDecls(filePath, node)
+	}
+
+	// Structs to generate, decided before scanning so referenced ones are known
+	included := b.includedStructs(node, fset, filePath)
+
 	// Aggregations are per-struct, so they will be initialized inside the struct loop
 	ast.Inspect(node, func(n ast.Node) bool {
+		if scanErr != nil {
+			return false
+		}
 		genDecl, ok := n.(*ast.GenDecl)
 		if !ok || genDecl.Tok != token.TYPE {
 			return true
@@ -252,31 +820,73 @@ func (b *modelBuilder) scanFile(filePath string) error {
 			if !ok {
 				continue
 			}
+			// Aliases to anonymous structs (type Point = struct{...}) are opt-in
+			isAlias := typeSpec.Assign.IsValid()
+			if isAlias && !b.config.Input.Struct.isIncludeAnonymous() {
+				continue
+			}
 
-			if !b.mustIncludeStruct(genDecl, typeSpec, fset, filePath) {
+			if !included[typeSpec] {
 				continue
 			}
 
 			structModel := &StructModel{
-				Name:       typeSpec.Name.Name,
-				File:       filePath,
-				LineNumber: fset.Position(typeSpec.Pos()).Line,
-				Constants:  []*ConstantOutput{},
-				Structs:    []*StructOutput{},
-				Getters:    []*GetterOutput{},
+				Name:            typeSpec.Name.Name,
+				File:            filePath,
+				LineNumber:      fset.Position(typeSpec.Pos()).Line,
+				BuildConstraint: buildConstraint,
+				Constants:       []*ConstantOutput{},
+				Structs:         []*StructOutput{},
+				Getters:         []*GetterOutput{},
+			}
+			// Struct name used in identifiers, see input.struct.strip_prefix
+			structIdent := b.config.Input.Struct.identifierName(structModel.Name)
+			if b.config.Output.isEmitSourceInfo() {
+				structModel.Source = b.sourceLocation(filePath, structModel.LineNumber)
+			}
+			if b.config.Output.isEmitSourceHash() {
+				structModel.SourceHash = structSourceHash(structType)
 			}
 
 			// Per-field+element constants cache
 			constantsByFieldAndElement := map[string]map[string]*ConstantOutput{}
 			// Per-field none cache
 			noneByFieldAndElement := map[string]map[string]*NoneOutput{}
+			// Per-field cache of none values promoted to constants by getters
+			promotedByFieldAndElement := map[string]map[string]*ConstantOutput{}
+			// Per-element typed key cache (element name -> key type)
+			keyTypeByElement := map[string]*KeyTypeOutput{}
 			// Per-element struct outputs cache (element name -> struct output)
 			structByElement := map[string]*StructOutput{}
 			// Per-field of struct-field outputs cache
 			structFieldByFieldAndElement := map[string]map[string]*FieldOutput{}
 
-			// Process fields
-			for _, field := range structType.Fields.List {
+			// Struct-level tags, read by joined elements
+			structTagText := structLevelTagText(structType.Fields.List)
+			// Fields dropped by their position, see input.field.skip_first and skip_last
+			skippedFields := b.skippedFields(structType.Fields.List)
+			// Reflect indexes of the fields, see output.emit_field_index
+			var fieldIndexes map[string]int
+			if b.config.Output.isEmitFieldIndex() {
+				fieldIndexes = structFieldIndexes(structType.Fields.List)
+			}
+
+			// Values of elements in doc mode keep the struct in the model, even
+			// without any code to generate
+			hasDocValues := false
+
+			// Output mode of every element for this struct, see //constago:mode
+			structMode := b.structModeDirective(genDecl, typeSpec, fset, filePath)
+
+			// Fields promoted from embedded structs, see input.field.flatten_embedded
+			fields := structType.Fields.List
+			if b.config.Input.Field.isFlattenEmbedded() {
+				fields = flattenEmbedded(fields, localTypes, map[string]bool{typeSpec.Name.Name: true})
+			}
+
+			// Process fields, one ordered list shared by the constants, the holder
+			// structs and the getters so they line up
+			for _, field := range b.orderFields(fields) {
 				// Skip anonymous fields
 				if len(field.Names) == 0 {
 					continue
@@ -285,28 +895,131 @@ func (b *modelBuilder) scanFile(filePath string) error {
 					continue
 				}
 
-				var tagText string
-				if field.Tag != nil {
-					tagText = strings.Trim(field.Tag.Value, "`")
-				}
+				tagText := fieldTagText(field)
+				isCollection := b.isCollectionType(field.Type)
+				// Category of the field constants, see output.group_by_tag
+				fieldGroup := b.fieldGroup(tagText)
+				// Declared type, only a string for elements in type mode
+				goType, _ := b.extractTypeInfo(field.Type, importIndex, modulePath)
 
 				for _, ident := range field.Names {
 					fieldName := ident.Name
+					if !b.mustIncludeFieldName(field, fieldName) || skippedFields[fieldName] {
+						continue
+					}
+					// Unexported fields get their own output when splitting by visibility
+					splitUnexported := b.config.Output.isSplitVisibility() && !ast.IsExported(fieldName)
+					addConstant := func(c *ConstantOutput) {
+						c.Group = fieldGroup
+						if splitUnexported {
+							c.Name = unexportName(c.Name)
+							structModel.UnexportedConstants = append(structModel.UnexportedConstants, c)
+							return
+						}
+						structModel.Constants = append(structModel.Constants, c)
+					}
+
+					// Values by element, recorded in the struct fields
+					fieldValues := map[string]string{}
 
 					// Build per-element artifacts
 					for i := range b.config.Elements {
 						el := &b.config.Elements[i]
-						value := b.computeElementValue(fieldName, tagText, el)
+						if !el.appliesToStruct(structModel.Name) {
+							continue
+						}
+						if el.Input.isExpandOneof() {
+							// Each allowed value gets a constant instead of the element value
+							for _, option := range b.oneofValues(tagText, el) {
+								constName := b.buildConstantName(el, packageName, structIdent, fieldName+" "+option)
+								if !isValidGoIdentifier(constName) {
+									b.model.AddError(filePath, fset.Position(field.Pos()).Line, fmt.Sprintf("oneof value %q of field %s doesn't make a valid constant name", option, fieldName))
+									continue
+								}
+								b.elementValues[el.Name]++
+								c := &ConstantOutput{Name: constName, Value: option}
+								b.recordAggregated(el, c)
+								addConstant(c)
+							}
+							continue
+						}
+						value, matched := b.computeElementValue(fieldName, goType, tagText, structTagText, el)
+						if b.isBlankFieldValue(fieldName, matched, el) {
+							// Tagged blank fields carry struct-level tags, e.g.
+							// _ struct{} `db:"users"`, so they're skipped quietly
+							if fieldName == "_" && field.Tag != nil {
+								continue
+							}
+							b.model.AddError(filePath, fset.Position(ident.Pos()).Line, fmt.Sprintf("field %s of struct %s has no usable value for element %s", fieldName, structModel.Name, el.Name))
+							continue
+						}
 						if value == "" {
 							continue
 						}
+						b.elementValues[el.Name]++
+						fieldValues[el.Name] = value
+
+						// Identifier part for the field, marking collections when configured
+						fieldPart := fieldName
+						if el.Output.CollectionSuffix != "" && isCollection {
+							fieldPart = fieldName + " " + el.Output.CollectionSuffix
+						}
+						if el.Output.isTypeSuffix() {
+							if typeName := typeSuffixName(goType); typeName != "" {
+								fieldPart += " " + typeName
+							}
+						}
 
-						switch el.Output.Mode {
+						outputMode := el.Output.Mode
+						if structMode != "" {
+							outputMode = structMode
+						}
+						switch outputMode {
 						case OutputModeConstant:
 							// Top-level constant name
-							constName := b.buildName(el.Output.Format.Prefix, structModel.Name, fieldName, el.Output.Format.Suffix, el.Output.Format.Struct)
+							namePart := fieldPart
+							if el.Output.isTypedKey() {
+								namePart = "Key " + fieldPart
+							}
+							constName := b.buildConstantName(el, packageName, structIdent, namePart)
 							c := &ConstantOutput{Name: constName, Value: value}
-							structModel.Constants = append(structModel.Constants, c)
+							b.recordAggregated(el, c)
+							c.Comment = b.constantComment(matched, el)
+							if el.Output.isTypedKey() {
+								// Typed keys share one named type per struct and element
+								kt, ok := keyTypeByElement[el.Name]
+								if !ok {
+									kt = &KeyTypeOutput{Name: b.buildElementName(el, packageName, structIdent, "Key")}
+									keyTypeByElement[el.Name] = kt
+									structModel.KeyTypes = append(structModel.KeyTypes, kt)
+								}
+								c.Type = kt.Name
+							}
+							addConstant(c)
+							if el.Output.isEmitValues() {
+								keyTypeByElement[el.Name].Values = append(keyTypeByElement[el.Name].Values, c.Name)
+							}
+							if el.Output.isEmitNormalized() {
+								// A normalized variant for case-insensitive comparisons,
+								// named after the normalization (e.g. JsonUserNameLower).
+								// It's left untyped, as it isn't a key of the typed set
+								nc := &ConstantOutput{
+									Name:  b.buildConstantName(el, packageName, structIdent, namePart+" "+string(el.Output.Normalization)),
+									Value: transformFieldValue(value, el.Output.Normalization, ""),
+								}
+								b.recordAggregated(el, nc)
+								addConstant(nc)
+							}
+							if el.Output.isEmitBoth() {
+								// The field name paired with the element value, untyped
+								// since the field name isn't a key
+								fc := &ConstantOutput{
+									Name:  b.buildConstantName(el, packageName, structIdent, namePart+" Field"),
+									Value: fieldName,
+								}
+								b.recordAggregated(el, fc)
+								addConstant(fc)
+							}
 							if _, ok := constantsByFieldAndElement[fieldName]; !ok {
 								constantsByFieldAndElement[fieldName] = map[string]*ConstantOutput{}
 							}
@@ -314,16 +1027,27 @@ func (b *modelBuilder) scanFile(filePath string) error {
 
 						case OutputModeStruct:
 							// Ensure struct output exists for this element
-							so, ok := structByElement[el.Name]
+							// Unexported fields are held by a separate unexported struct
+							structKey := el.Name
+							if splitUnexported {
+								structKey += " unexported"
+							}
+							so, ok := structByElement[structKey]
 							if !ok {
-								structName := b.buildName(el.Output.Format.Prefix, structModel.Name, "", el.Output.Format.Suffix, el.Output.Format.Struct)
+								structName := b.buildElementName(el, packageName, structIdent, "")
+								if splitUnexported {
+									structName = unexportName(structName)
+								}
 								so = &StructOutput{Name: structName, Package: packageName}
-								structByElement[el.Name] = so
+								structByElement[structKey] = so
 								structModel.Structs = append(structModel.Structs, so)
 							}
 							// Field name inside struct uses holder format
-							fieldConstName := b.buildName("", fieldName, "", "", el.Output.Format.Holder)
+							fieldConstName := b.buildName("", fieldPart, "", "", el.Output.Format.Holder, false)
 							fieldOutput := &FieldOutput{StructName: so.Name, Name: fieldConstName, Value: value}
+							if el.Output.StructFieldTags == StructFieldTagsCopy {
+								fieldOutput.Tag = tagText
+							}
 							so.Fields = append(so.Fields, fieldOutput)
 
 							if _, ok := structFieldByFieldAndElement[fieldName]; !ok {
@@ -335,22 +1059,61 @@ func (b *modelBuilder) scanFile(filePath string) error {
 								noneByFieldAndElement[fieldName] = map[string]*NoneOutput{}
 							}
 							noneByFieldAndElement[fieldName][el.Name] = &NoneOutput{Name: fieldName, Value: value}
+						case OutputModeDoc:
+							// Only recorded in the struct fields
+							hasDocValues = true
 						}
 					}
 
-					// Build getters for this field
-					for gi := range b.config.Getters {
-						g := &b.config.Getters[gi]
-						getterName := b.buildName(g.Output.Prefix, fieldName, g.Output.Suffix, "", g.Output.Format)
-						getter := &GetterOutput{Name: getterName}
+					if len(fieldValues) > 0 {
+						structModel.Fields = append(structModel.Fields, &FieldModel{Name: fieldName, Values: fieldValues})
+					}
 
-						for _, ret := range g.Returns {
-							// Handle special returns
+					// JSON type of the field for schema generation, e.g. JsonTypeUserAge = "number"
+					if b.config.Output.isEmitJSONType() {
+						if jsonType := jsonTypeOf(field.Type, packageTypes, 0); jsonType != "" {
+							addConstant(&ConstantOutput{
+								Name:  b.buildName("JsonType", structIdent, fieldName, "", ConstantFormatPascal, false),
+								Value: jsonType,
+							})
+						}
+					}
+
+					// Index of the field for reflect, e.g. UserNameIndex = 0. Fields
+					// promoted from embedded structs have no index of their own.
+					if index, ok := fieldIndexes[fieldName]; ok && b.config.Output.isEmitFieldIndex() {
+						addConstant(&ConstantOutput{
+							Name:    b.buildName("", structIdent, fieldName, "Index", ConstantFormatPascal, false),
+							Value:   strconv.Itoa(index),
+							Literal: true,
+						})
+					}
+
+					// Build getters for this field. Methods can't be declared on
+					// an alias of an anonymous struct, so aliases get no getters.
+					getters := b.config.Getters
+					if isAlias {
+						getters = nil
+					}
+					for gi := range getters {
+						g := &getters[gi]
+						if !g.appliesToStruct(structModel.Name) {
+							continue
+						}
+						getterName := b.buildName(g.Output.Prefix, fieldName, "", g.Output.Suffix, g.Output.Format, g.Output.isPrefixLiteral())
+						getter := &GetterOutput{Name: getterName, Receiver: g.Output.ReceiverType}
+
+						for _, ret := range g.Returns {
+							// Handle special returns
 							if strings.HasPrefix(ret, ":") {
 								if ret == ":value" {
 									// Create ValueOutput for field value return
 									valueOutput := b.createValueOutput(field, fieldName, packageName, importIndex, modulePath, moduleDir)
 									if valueOutput != nil {
+										if b.config.Output.isStrict() && scanErr == nil && valueOutput.isUnresolved(packageName) {
+											scanErr = fmt.Errorf("%s:%d: type %s of field %s.%s can't be resolved to an import path (output.strict)",
+												filePath, fset.Position(field.Pos()).Line, valueOutput.TypeName, structModel.Name, fieldName)
+										}
 										getter.Returns = append(getter.Returns, &ReturnOutput{Value: valueOutput})
 									}
 								}
@@ -360,33 +1123,601 @@ func (b *modelBuilder) scanFile(filePath string) error {
 							// Prefer constant if produced
 							if cm, ok := constantsByFieldAndElement[fieldName][ret]; ok {
 								getter.Returns = append(getter.Returns, &ReturnOutput{Constant: cm})
+							} else if no, ok := noneByFieldAndElement[fieldName][ret]; ok && g.Output.isPromoteNone() {
+								// Promote the none value to a constant so the getter can reference it
+								c, ok := promotedByFieldAndElement[fieldName][ret]
+								if !ok {
+									el := b.config.findElement(ret)
+									constName := b.buildConstantName(el, packageName, structIdent, fieldName)
+									c = &ConstantOutput{Name: constName, Value: no.Value, Group: fieldGroup}
+									structModel.Constants = append(structModel.Constants, c)
+									if _, ok := promotedByFieldAndElement[fieldName]; !ok {
+										promotedByFieldAndElement[fieldName] = map[string]*ConstantOutput{}
+									}
+									promotedByFieldAndElement[fieldName][ret] = c
+								}
+								getter.Returns = append(getter.Returns, &ReturnOutput{Constant: c, Reference: true})
 							} else if no, ok := noneByFieldAndElement[fieldName][ret]; ok {
 								// Since the name is not set in a Constant or a Field, then the name should be the
-								// element name
+								// element name, unless output.none_name overrides it
 								no.Name = ret
+								if el := b.config.findElement(ret); el != nil && el.Output.NoneName != "" {
+									no.Name = el.Output.NoneName
+								}
 								getter.Returns = append(getter.Returns, &ReturnOutput{None: no})
 							} else if so, ok := structFieldByFieldAndElement[fieldName][ret]; ok {
+								// Struct mode returns inline the holder field value, so the
+								// getter doesn't depend on the holder variable or its field
+								// format (e.g. camel holder fields)
 								getter.Returns = append(getter.Returns, &ReturnOutput{Field: so})
+							} else if _, ok := fieldValues[ret]; ok && scanErr == nil {
+								// The field has a value for the element, but no constant, none
+								// value or holder field to return, e.g. in doc mode set by a
+								// struct directive, so the getter would be silently dropped
+								mode := b.config.findElement(ret).Output.Mode
+								if structMode != "" {
+									mode = structMode
+								}
+								scanErr = fmt.Errorf("%s:%d: getter %s can't return element %s of field %s.%s in %s mode, only constant, struct and none modes can be returned",
+									filePath, fset.Position(field.Pos()).Line, g.Name, ret, structModel.Name, fieldName, mode)
 							}
 						}
 
 						// Add getter if all returns are satisfied
 						if len(getter.Returns) == len(g.Returns) {
+							for ri, name := range g.Output.ResultNames {
+								getter.Returns[ri].ResultName = name
+							}
+							if g.Output.isErrorReturn() {
+								errorExpr, err := renderErrorExpr(g, structModel.Name, fieldName)
+								if err != nil && scanErr == nil {
+									scanErr = fmt.Errorf("%s:%d: %w", filePath, fset.Position(field.Pos()).Line, err)
+								}
+								getter.ErrorExpr = errorExpr
+							}
 							structModel.Getters = append(structModel.Getters, getter)
+							b.getterOutputs[g.Name]++
 						}
 					}
 				}
 			}
-			if len(structModel.Constants) > 0 || len(structModel.Structs) > 0 || len(structModel.Getters) > 0 {
+			if !isStringBlank(b.config.Output.GroupByTag) {
+				structModel.Constants = groupConstants(structModel.Constants)
+				structModel.UnexportedConstants = groupConstants(structModel.UnexportedConstants)
+			}
+			hasOutput := len(structModel.Constants) > 0 || len(structModel.UnexportedConstants) > 0 || len(structModel.Structs) > 0 || len(structModel.Getters) > 0
+			// Marker structs without fields only get the struct name constant
+			isEmpty := len(structType.Fields.List) == 0
+			if !hasOutput && !hasDocValues && !isEmpty {
+				// Included structs whose fields are all excluded or yield no value
+				message := fmt.Sprintf("struct %s is included but yields no output", structModel.Name)
+				if b.config.Input.isFailEmptyStructs() && scanErr == nil {
+					scanErr = fmt.Errorf("%s:%d: %s (input.fail_empty_structs)", filePath, structModel.LineNumber, message)
+				} else if b.config.Input.isWarnEmptyStructs() {
+					b.model.AddError(filePath, structModel.LineNumber, message)
+				}
+			}
+			if b.config.Output.isEmitStructConstant() && (hasOutput || (isEmpty && b.config.Output.isEmitEmptyStructs())) {
+				c := &ConstantOutput{Name: b.buildName("", structIdent, "StructName", "", ConstantFormatPascal, false), Value: structModel.Name}
+				for _, fc := range append(slices.Clone(structModel.Constants), structModel.UnexportedConstants...) {
+					if fc.Name == c.Name && scanErr == nil {
+						scanErr = fmt.Errorf("%s:%d: constant %s naming struct %s collides with a field constant (output.emit_struct_constant)", filePath, structModel.LineNumber, c.Name, structModel.Name)
+					}
+				}
+				structModel.Constants = append([]*ConstantOutput{c}, structModel.Constants...)
+				hasOutput = true
+			}
+			if hasOutput || hasDocValues {
 				b.model.AddStruct(packagePath, packageName, structModel)
 			}
 		}
 		return true
 	})
 
+	if scanErr == nil && b.config.Input.isIncludeMapVars() {
+		b.scanMapVars(node, fset, filePath, packagePath, packageName)
+	}
+
+	return scanErr
+}
+
+// scanMapVars adds constants for the string keys of the package-level map
+// literals, named after the variable and the key, e.g. ColorsDarkBlue for
+// the "dark-blue" key of var colors = map[string]int{...}
+func (b *modelBuilder) scanMapVars(node *ast.File, fset *token.FileSet, filePath string, packagePath string, packageName string) {
+	for _, decl := range node.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok || genDecl.Tok != token.VAR {
+			continue
+		}
+		for _, spec := range genDecl.Specs {
+			valueSpec, ok := spec.(*ast.ValueSpec)
+			if !ok {
+				continue
+			}
+			for i, ident := range valueSpec.Names {
+				if ident.Name == "_" || i >= len(valueSpec.Values) {
+					continue
+				}
+				lit, ok := valueSpec.Values[i].(*ast.CompositeLit)
+				if !ok {
+					continue
+				}
+				mapType, ok := lit.Type.(*ast.MapType)
+				if !ok {
+					continue
+				}
+				if keyType, ok := mapType.Key.(*ast.Ident); !ok || keyType.Name != "string" {
+					continue
+				}
+
+				mapKeys := &MapKeysModel{
+					Name:       ident.Name,
+					File:       filePath,
+					LineNumber: fset.Position(ident.Pos()).Line,
+				}
+				names := map[string]bool{}
+				for _, elt := range lit.Elts {
+					kv, ok := elt.(*ast.KeyValueExpr)
+					if !ok {
+						continue
+					}
+					keyLit, ok := kv.Key.(*ast.BasicLit)
+					if !ok || keyLit.Kind != token.STRING {
+						continue
+					}
+					key, err := strconv.Unquote(keyLit.Value)
+					if err != nil {
+						continue
+					}
+					name := b.buildName("", ident.Name, key, "", ConstantFormatPascal, false)
+					line := fset.Position(keyLit.Pos()).Line
+					if !isValidGoIdentifier(name) {
+						b.model.AddError(filePath, line, fmt.Sprintf("key %q of map %s doesn't make a valid constant name", key, ident.Name))
+						continue
+					}
+					if names[name] {
+						b.model.AddError(filePath, line, fmt.Sprintf("key %q of map %s collides with another key as %s", key, ident.Name, name))
+						continue
+					}
+					names[name] = true
+					mapKeys.Constants = append(mapKeys.Constants, &ConstantOutput{Name: name, Value: key})
+				}
+				if len(mapKeys.Constants) > 0 {
+					b.model.AddMapKeys(packagePath, packageName, mapKeys)
+				}
+			}
+		}
+	}
+}
+
+// orderFields returns the struct fields in the order configured by
+// output.field_order. Fields declaring several names are split so each name
+// is ordered on its own.
+func (b *modelBuilder) orderFields(fields []*ast.Field) []*ast.Field {
+	order := b.config.Output.FieldOrder
+	if order == "" || order == FieldOrderSource {
+		return fields
+	}
+
+	structTagText := structLevelTagText(fields)
+
+	var ordered []*ast.Field
+	for _, field := range fields {
+		if len(field.Names) == 0 {
+			ordered = append(ordered, field)
+			continue
+		}
+		for _, ident := range field.Names {
+			ordered = append(ordered, &ast.Field{Doc: field.Doc, Names: []*ast.Ident{ident}, Type: field.Type, Tag: field.Tag, Comment: field.Comment})
+		}
+	}
+
+	sortKey := func(field *ast.Field) string {
+		if len(field.Names) == 0 {
+			return ""
+		}
+		fieldName := field.Names[0].Name
+		if order == FieldOrderTag {
+			tagText := fieldTagText(field)
+			goType, _ := b.extractTypeInfo(field.Type, nil, "")
+			// Use the value of the first element producing one
+			for i := range b.config.Elements {
+				if value, _ := b.computeElementValue(fieldName, goType, tagText, structTagText, &b.config.Elements[i]); value != "" {
+					return value
+				}
+			}
+		}
+		return fieldName
+	}
+
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return sortKey(ordered[i]) < sortKey(ordered[j])
+	})
+
+	return ordered
+}
+
+// aggregateConstants moves the constants of the elements with
+// output.aggregate_to to the package in that directory, under a struct named
+// after the package and the struct they come from
+func (b *modelBuilder) aggregateConstants() error {
+	if len(b.aggregatedConstants) == 0 {
+		return nil
+	}
+	targets := map[*ConfigTag]string{}
+	for i := range b.config.Elements {
+		el := &b.config.Elements[i]
+		if isStringBlank(el.Output.AggregateTo) {
+			continue
+		}
+		dir, err := filepath.Abs(filepath.Join(b.config.Input.Dir, el.Output.AggregateTo))
+		if err != nil {
+			return fmt.Errorf("failed to resolve aggregate_to of element %s: %w", el.Name, err)
+		}
+		targets[el] = filepath.ToSlash(dir)
+	}
+
+	paths := make([]string, 0, len(b.model.Packages))
+	for path := range b.model.Packages {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	// The structs are listed first, since the target packages get new ones
+	type source struct {
+		pkg         *PackageModel
+		structModel *StructModel
+	}
+	var sources []source
+	for _, path := range paths {
+		for _, structModel := range b.model.Packages[path].Structs {
+			sources = append(sources, source{pkg: b.model.Packages[path], structModel: structModel})
+		}
+	}
+
+	aggregated := map[string]*StructModel{}
+	// Path of the package giving each qualifier in a target, since the
+	// constants of packages with the same name couldn't be told apart
+	qualifiers := map[string]string{}
+	move := func(src source, constants []*ConstantOutput) ([]*ConstantOutput, error) {
+		kept := constants[:0]
+		for _, c := range constants {
+			el := b.aggregatedConstants[c]
+			target, ok := targets[el]
+			if !ok {
+				kept = append(kept, c)
+				continue
+			}
+			qualifier := target + "\x00" + src.pkg.Name
+			if previous, ok := qualifiers[qualifier]; !ok {
+				qualifiers[qualifier] = src.pkg.Path
+			} else if previous != src.pkg.Path {
+				return nil, fmt.Errorf("packages %s and %s are both named %s, so their constants can't be told apart in %s (output.aggregate_to of element %s)",
+					previous, src.pkg.Path, src.pkg.Name, target, el.Name)
+			}
+			key := target + "\x00" + src.pkg.Path + "\x00" + src.structModel.Name
+			structModel, ok := aggregated[key]
+			if !ok {
+				packageName, err := b.aggregatePackageName(target)
+				if err != nil {
+					return nil, err
+				}
+				structModel = &StructModel{
+					Name:       src.pkg.Name + "." + src.structModel.Name,
+					File:       src.structModel.File,
+					LineNumber: src.structModel.LineNumber,
+					Source:     src.structModel.Source,
+				}
+				aggregated[key] = structModel
+				pkg := b.model.packageModel(target, packageName)
+				pkg.Structs = append(pkg.Structs, structModel)
+			}
+			structModel.Constants = append(structModel.Constants, c)
+		}
+		return kept, nil
+	}
+
+	for _, src := range sources {
+		var err error
+		if src.structModel.Constants, err = move(src, src.structModel.Constants); err != nil {
+			return err
+		}
+		if src.structModel.UnexportedConstants, err = move(src, src.structModel.UnexportedConstants); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// recordAggregated records the constant of an element when it's moved to
+// another package by output.aggregate_to
+func (b *modelBuilder) recordAggregated(el *ConfigTag, c *ConstantOutput) {
+	if isStringBlank(el.Output.AggregateTo) {
+		return
+	}
+	if b.aggregatedConstants == nil {
+		b.aggregatedConstants = map[*ConstantOutput]*ConfigTag{}
+	}
+	b.aggregatedConstants[c] = el
+}
+
+// aggregatePackageName returns the name of the package in dir, as declared by
+// its Go files, or else the base name of dir
+func (b *modelBuilder) aggregatePackageName(dir string) (string, error) {
+	if pkg, ok := b.model.Packages[dir]; ok {
+		return pkg.Name, nil
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	sort.Strings(files)
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") || b.config.Output.isGeneratedFile(file) {
+			continue
+		}
+		node, err := parser.ParseFile(token.NewFileSet(), file, nil, parser.PackageClauseOnly)
+		if err == nil && node.Name != nil {
+			return node.Name.Name, nil
+		}
+	}
+	name := filepath.Base(dir)
+	if !isValidGoIdentifier(name) {
+		return "", fmt.Errorf("aggregate_to directory %s doesn't make a valid package name", dir)
+	}
+	return name, nil
+}
+
+// dropGeneratedHolders removes the structs named like a holder struct
+// generated for another struct of their package, e.g. a JsonUser type copied
+// from a previous output without its header, so holders are never scanned
+// again. Such a type would conflict with the generated holder anyway.
+func (b *modelBuilder) dropGeneratedHolders() {
+	for _, pkg := range b.model.Packages {
+		holders := map[string]bool{}
+		for _, s := range pkg.Structs {
+			for _, so := range s.Structs {
+				holders[so.Name] = true
+			}
+		}
+		if len(holders) == 0 {
+			continue
+		}
+		kept := make([]*StructModel, 0, len(pkg.Structs))
+		for _, s := range pkg.Structs {
+			if !holders[s.Name] {
+				kept = append(kept, s)
+			}
+		}
+		pkg.Structs = kept
+	}
+}
+
+// checkDuplicateStructs fails when a package declares the same struct more
+// than once, which only compiles when each declaration is guarded by a
+// different build constraint. Generating both would produce duplicated
+// identifiers, so the conflicting definitions are reported instead.
+func (b *modelBuilder) checkDuplicateStructs() error {
+	paths := make([]string, 0, len(b.model.Packages))
+	for path := range b.model.Packages {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		seen := map[string]*StructModel{}
+		for _, structModel := range b.model.Packages[path].Structs {
+			previous, ok := seen[structModel.Name]
+			if !ok {
+				seen[structModel.Name] = structModel
+				continue
+			}
+			return fmt.Errorf("struct %s is declared in %s (build constraint %q) and %s (build constraint %q), exclude one of the files or add the //constago:exclude directive to one of the definitions",
+				structModel.Name, previous.File, previous.BuildConstraint, structModel.File, structModel.BuildConstraint)
+		}
+	}
+	return nil
+}
+
+// checkStrippedStructNames fails when input.struct.strip_prefix or
+// strip_suffix leave two structs of a package with the same name, since every
+// identifier generated for them, like constants, holder structs and key
+// types, would be declared twice, e.g. for User and UserModel with the Model
+// suffix stripped
+func (b *modelBuilder) checkStrippedStructNames() error {
+	structConfig := &b.config.Input.Struct
+	if isStringBlank(structConfig.StripPrefix) && isStringBlank(structConfig.StripSuffix) {
+		return nil
+	}
+
+	paths := make([]string, 0, len(b.model.Packages))
+	for path := range b.model.Packages {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		seen := map[string]*StructModel{}
+		for _, structModel := range b.model.Packages[path].Structs {
+			if !structModel.hasCode() {
+				continue
+			}
+			ident := structConfig.identifierName(structModel.Name)
+			previous, ok := seen[ident]
+			if !ok {
+				seen[ident] = structModel
+				continue
+			}
+			if previous.Name != structModel.Name {
+				return fmt.Errorf("structs %s and %s of %s are both named %s in generated identifiers (input.struct.strip_prefix and strip_suffix)",
+					previous.Name, structModel.Name, path, ident)
+			}
+		}
+	}
+	return nil
+}
+
+// checkReceiverTypes fails when a getter with output.receiver_type matches
+// several structs of a package, whose methods would collide on the same type,
+// no struct at all, declaring nothing on it, or a package not declaring the
+// type. It also fails when getters declare the same method on a receiver.
+func (b *modelBuilder) checkReceiverTypes() error {
+	paths := make([]string, 0, len(b.model.Packages))
+	for path := range b.model.Packages {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for i := range b.config.Getters {
+		g := &b.config.Getters[i]
+		if isStringBlank(g.Output.ReceiverType) {
+			continue
+		}
+		matched := 0
+		for _, path := range paths {
+			var matching []*StructModel
+			for _, structModel := range b.model.Packages[path].Structs {
+				if g.appliesToStruct(structModel.Name) {
+					matching = append(matching, structModel)
+				}
+			}
+			if len(matching) > 1 {
+				return fmt.Errorf("getter %s declares its methods on %s, but structs %s and %s of package %s both match structs_matching %q, only one can (getters[%d].output.receiver_type)",
+					g.Name, g.Output.ReceiverType, matching[0].Name, matching[1].Name, path, g.StructsMatching, i)
+			}
+			// External test packages only have test files, not looked up
+			pkgName := b.model.Packages[path].Name
+			if len(matching) == 1 && !strings.HasSuffix(pkgName, "_test") {
+				if _, ok := b.dirTypeDecls(path, pkgName)[g.Output.ReceiverType]; !ok {
+					return fmt.Errorf("getter %s declares its methods on %s, but package %s doesn't declare it (getters[%d].output.receiver_type)",
+						g.Name, g.Output.ReceiverType, path, i)
+				}
+			}
+			matched += len(matching)
+		}
+		if matched == 0 {
+			return fmt.Errorf("getter %s declares its methods on %s, but no struct matches structs_matching %q (getters[%d].output.receiver_type)",
+				g.Name, g.Output.ReceiverType, g.StructsMatching, i)
+		}
+	}
+
+	for _, path := range paths {
+		// Struct declaring each method, by receiver type
+		declared := map[string]map[string]string{}
+		for _, structModel := range b.model.Packages[path].Structs {
+			for _, getter := range structModel.Getters {
+				receiver := getter.Receiver
+				if receiver == "" {
+					receiver = structModel.Name
+				}
+				if declared[receiver] == nil {
+					declared[receiver] = map[string]string{}
+				}
+				if previous, ok := declared[receiver][getter.Name]; ok {
+					if previous == structModel.Name {
+						return fmt.Errorf("getters of struct %s declare method %s twice on %s of package %s (getters output.prefix and output.suffix)",
+							previous, getter.Name, receiver, path)
+					}
+					return fmt.Errorf("getters of structs %s and %s both declare method %s on %s of package %s (getters output.receiver_type)",
+						previous, structModel.Name, getter.Name, receiver, path)
+				}
+				declared[receiver][getter.Name] = structModel.Name
+			}
+		}
+	}
+	return nil
+}
+
+// dedupConstants keeps a single declaration of the constants sharing name and
+// value in a package, which happens when output.format.omit_struct drops the
+// struct name, and fails when constants with the same name differ, including
+// the key constants of map variables
+func (b *modelBuilder) dedupConstants() error {
+	paths := make([]string, 0, len(b.model.Packages))
+	for path := range b.model.Packages {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		type declared struct {
+			constant *ConstantOutput
+			owner    string
+		}
+		seen := map[string]declared{}
+		dedup := func(owner string, constants []*ConstantOutput) ([]*ConstantOutput, error) {
+			kept := constants[:0]
+			for _, c := range constants {
+				previous, ok := seen[c.Name]
+				if !ok {
+					seen[c.Name] = declared{constant: c, owner: owner}
+					kept = append(kept, c)
+					continue
+				}
+				if previous.constant.Value != c.Value || previous.constant.Type != c.Type || previous.constant.Literal != c.Literal {
+					return nil, fmt.Errorf("constant %s of %s collides with the one of %s in %s, with values %q and %q",
+						c.Name, owner, previous.owner, path, c.Value, previous.constant.Value)
+				}
+			}
+			return kept, nil
+		}
+		pkg := b.model.Packages[path]
+		for _, structModel := range pkg.Structs {
+			owner := "struct " + structModel.Name
+			var err error
+			if structModel.Constants, err = dedup(owner, structModel.Constants); err != nil {
+				return err
+			}
+			if structModel.UnexportedConstants, err = dedup(owner, structModel.UnexportedConstants); err != nil {
+				return err
+			}
+		}
+		keptMaps := pkg.MapKeys[:0]
+		for _, mapKeys := range pkg.MapKeys {
+			var err error
+			if mapKeys.Constants, err = dedup("map "+mapKeys.Name, mapKeys.Constants); err != nil {
+				return err
+			}
+			if len(mapKeys.Constants) > 0 {
+				keptMaps = append(keptMaps, mapKeys)
+			}
+		}
+		pkg.MapKeys = keptMaps
+	}
+	return nil
+}
+
+// fileBuildConstraint returns the //go:build expression of a parsed file, if any
+func fileBuildConstraint(node *ast.File) string {
+	for _, cg := range node.Comments {
+		if cg.Pos() >= node.Package {
+			break
+		}
+		for _, c := range cg.List {
+			if constraint.IsGoBuild(c.Text) {
+				if expr, err := constraint.Parse(c.Text); err == nil {
+					return expr.String()
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// isConstagoGenerated reports whether a parsed file carries the header
+// written by the code template
+func isConstagoGenerated(node *ast.File) bool {
+	for _, cg := range node.Comments {
+		if cg.Pos() >= node.Package {
+			break
+		}
+		for _, c := range cg.List {
+			if strings.HasPrefix(c.Text, generatedHeader) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // extractPackagePath from file path
 func (b *modelBuilder) extractPackagePath(filePath string) string {
 	abs, err := filepath.Abs(filePath)
@@ -399,34 +1730,45 @@ func (b *modelBuilder) extractPackagePath(filePath string) string {
 	return dir
 }
 
-// mustIncludeField decides if a field should be processed according to config and tags
-func (b *modelBuilder) mustIncludeField(field *ast.Field) bool {
-	// Parse tags
-	var tag reflect.StructTag
-	if field.Tag != nil {
-		tag = parseStructTags(strings.Trim(field.Tag.Value, "`"))
+// sourceLocation formats a file and line relative to the input dir
+func (b *modelBuilder) sourceLocation(filePath string, line int) string {
+	rel, err := filepath.Rel(b.config.Input.Dir, filePath)
+	if err != nil {
+		rel = filePath
 	}
-	constagoTag, hasConstago := lookupTag(tag, "constago")
+	return fmt.Sprintf("%s:%d", filepath.ToSlash(rel), line)
+}
 
-	if hasConstago && constagoTag == "exclude" {
-		return false
-	}
-	if hasConstago && constagoTag == "include" {
-		return true
-	}
-	if b.config.Input.Field.isExplicit() && !hasConstago {
-		return false
+// mustIncludeField decides if a field should be processed according to config
+// and tags, which is when any of its names is included
+func (b *modelBuilder) mustIncludeField(field *ast.Field) bool {
+	// Skip anonymous fields for name rules
+	if len(field.Names) == 0 {
+		return b.fieldTagDecision(field) != fieldExcluded
 	}
-	if !b.config.Input.Field.isIncludeUnexported() && len(field.Names) > 0 && !ast.IsExported(field.Names[0].Name) {
-		return false
+	for _, ident := range field.Names {
+		if b.mustIncludeFieldName(field, ident.Name) {
+			return true
+		}
 	}
+	return false
+}
 
-	// Skip anonymous fields for regex matching
-	if len(field.Names) == 0 {
+// mustIncludeFieldName decides if one of the names of a field is processed.
+// Names sharing a declaration (e.g. Name, age string) are decided one by one,
+// so the unexported and regex rules apply to each of them, whatever the
+// struct holding them, even one included by a directive.
+func (b *modelBuilder) mustIncludeFieldName(field *ast.Field, fieldName string) bool {
+	switch b.fieldTagDecision(field) {
+	case fieldExcluded:
+		return false
+	case fieldIncluded:
 		return true
 	}
 
-	fieldName := field.Names[0].Name
+	if !b.config.Input.Field.isIncludeUnexported() && !ast.IsExported(fieldName) {
+		return false
+	}
 
 	// Check include_only (whitelist) regex pattern
 	if strings.TrimSpace(b.config.Input.Field.Only) != "" {
@@ -436,37 +1778,121 @@ func (b *modelBuilder) mustIncludeField(field *ast.Field) bool {
 		}
 	}
 
-	// Check include_except (blacklist) regex pattern
-	if strings.TrimSpace(b.config.Input.Field.Except) != "" {
-		matched, err := regexp.MatchString(b.config.Input.Field.Except, fieldName)
-		if err == nil && matched {
-			return false
+	// Check include_except (blacklist) regex pattern
+	if strings.TrimSpace(b.config.Input.Field.Except) != "" {
+		matched, err := regexp.MatchString(b.config.Input.Field.Except, fieldName)
+		if err == nil && matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Decisions taken by the constago tag of a field before its name rules
+const (
+	fieldUndecided = iota
+	fieldIncluded
+	fieldExcluded
+)
+
+// fieldTagDecision reads the constago tag of a field, or else the directives
+// of its doc or line comment, excluding the undecided fields when
+// input.field.explicit is set
+func (b *modelBuilder) fieldTagDecision(field *ast.Field) int {
+	tag := parseStructTags(fieldTagText(field))
+	constagoTag, hasConstago := b.lookupTag(tag, "constago")
+
+	if hasConstago && constagoTag == "exclude" {
+		return fieldExcluded
+	}
+	if hasConstago && constagoTag == "include" {
+		return fieldIncluded
+	}
+	if !hasConstago {
+		includeDirective, excludeDirective := commentDirectives(field.Doc, field.Comment)
+		if excludeDirective {
+			return fieldExcluded
+		}
+		if includeDirective {
+			return fieldIncluded
+		}
+	}
+	if b.config.Input.Field.isExplicit() && !hasConstago {
+		return fieldExcluded
+	}
+	return fieldUndecided
+}
+
+// skippedFields returns the names of the first input.field.skip_first and the
+// last input.field.skip_last includable fields, in source order
+func (b *modelBuilder) skippedFields(fields []*ast.Field) map[string]bool {
+	skipFirst := b.config.Input.Field.SkipFirst
+	skipLast := b.config.Input.Field.SkipLast
+	if skipFirst <= 0 && skipLast <= 0 {
+		return nil
+	}
+
+	var names []string
+	for _, field := range fields {
+		if len(field.Names) == 0 || !b.mustIncludeField(field) {
+			continue
+		}
+		for _, ident := range field.Names {
+			if b.mustIncludeFieldName(field, ident.Name) {
+				names = append(names, ident.Name)
+			}
+		}
+	}
+
+	skipped := map[string]bool{}
+	for i, name := range names {
+		if i < skipFirst || i >= len(names)-skipLast {
+			skipped[name] = true
 		}
 	}
+	return skipped
+}
 
-	return true
+// tagMatch is the tag an element value is taken from, e.g. the json key and
+// the name,omitempty value of json:"name,omitempty"
+type tagMatch struct {
+	key   string
+	value string
 }
 
-// computeElementValue computes element value considering mode, tag priority and transforms
-func (b *modelBuilder) computeElementValue(fieldName string, tagText string, el *ConfigTag) string {
+// computeElementValue computes element value considering mode, tag priority
+// and transforms, returning the tag it's taken from, or nil when it doesn't
+// come from a tag, e.g. when it's mapped, joined or taken from the field name
+func (b *modelBuilder) computeElementValue(fieldName string, goType string, tagText string, structTagText string, el *ConfigTag) (string, *tagMatch) {
+	// Values mapped in the config override the tag and field resolution
+	if v, ok := el.Input.ValueMap[fieldName]; ok {
+		return v, nil
+	}
+
+	// Joined values combine several tags instead of taking the first match
+	if len(el.Input.Join.Tags) > 0 {
+		return b.joinTagValues(tagText, structTagText, el), nil
+	}
+
 	// helper: pick first non-empty tag value by priority
-	getFromTags := func() (string, bool) {
+	getFromTags := func() (string, *tagMatch, bool) {
 		if tagText == "" {
-			return "", false
+			return "", nil, false
 		}
 		tags := parseStructTags(tagText)
 		for _, key := range el.Input.TagPriority {
 			if key == ":field" {
 				// special pseudo-tag: refers to field name
-				return fieldName, true
+				return fieldName, nil, true
 			}
-			if v, ok := lookupTag(tags, key); ok {
-				// Use the value up to first comma (e.g., json:"name,omitempty")
-				parts := strings.SplitN(v, ",", 2)
-				return parts[0], true
+			if raw, ok := b.lookupTag(tags, key); ok {
+				if v, ok := elementTagValue(key, raw, el); ok {
+					return v, &tagMatch{key: key, value: raw}, true
+				}
 			}
 		}
-		return "", false
+		return "", nil, false
 	}
 
 	applyTransform := func(s string, cfg *ConfigTag) string {
@@ -476,32 +1902,129 @@ func (b *modelBuilder) computeElementValue(fieldName string, tagText string, el
 
 	switch el.Input.Mode {
 	case InputModeTypeTag:
-		if v, ok := getFromTags(); ok {
+		if v, matched, ok := getFromTags(); ok {
 			if el.Output.Transform.TagValues != nil && *el.Output.Transform.TagValues {
-				return applyTransform(v, el)
+				return applyTransform(v, el), matched
 			}
-			return v
+			return v, matched
 		}
-		return ""
+		return "", nil
 	case InputModeTypeField:
-		return applyTransform(fieldName, el)
+		return applyTransform(fieldName, el), nil
 	case InputModeTypeTagThenField:
-		if v, ok := getFromTags(); ok {
+		if v, matched, ok := getFromTags(); ok {
 			if el.Output.Transform.TagValues != nil && *el.Output.Transform.TagValues {
-				return applyTransform(v, el)
+				return applyTransform(v, el), matched
 			}
-			return v
+			return v, matched
 		}
-		return applyTransform(fieldName, el)
+		return applyTransform(fieldName, el), nil
+	case InputModeTypeGoType:
+		// The declared type is kept as written, e.g. []string or *time.Time
+		return goType, nil
 	default:
-		return ""
+		return "", nil
+	}
+}
+
+// isBlankFieldValue reports whether the element value is taken from the
+// blank identifier _ or a field name transforming to an empty string, given
+// the tag it's matched from by computeElementValue
+func (b *modelBuilder) isBlankFieldValue(fieldName string, matched *tagMatch, el *ConfigTag) bool {
+	if el.Input.Mode != InputModeTypeField && el.Input.Mode != InputModeTypeTagThenField {
+		return false
+	}
+	if _, ok := el.Input.ValueMap[fieldName]; ok || len(el.Input.Join.Tags) > 0 {
+		return false
+	}
+	if matched != nil {
+		return false
+	}
+	return fieldName == "_" || transformFieldValue(fieldName, el.Output.Transform.ValueCase, el.Output.Transform.ValueSeparator) == ""
+}
+
+// joinTagValues joins the values of the element join tags, each read from
+// the field tags or else from the struct-level ones. Fields missing any of the
+// tags get no value.
+func (b *modelBuilder) joinTagValues(tagText string, structTagText string, el *ConfigTag) string {
+	fieldTags := parseStructTags(tagText)
+	structTags := parseStructTags(structTagText)
+
+	values := make([]string, 0, len(el.Input.Join.Tags))
+	for _, key := range el.Input.Join.Tags {
+		v, ok := b.lookupTag(fieldTags, key)
+		if !ok {
+			v, ok = b.lookupTag(structTags, key)
+		}
+		if ok {
+			v, ok = elementTagValue(key, v, el)
+		}
+		if !ok {
+			return ""
+		}
+		values = append(values, v)
+	}
+	return strings.Join(values, el.Input.Join.Separator)
+}
+
+// structLevelTagText returns the tags declared on blank fields, the idiom used
+// for struct-level tags (e.g. _ struct{} `db:"users"`)
+func structLevelTagText(fields []*ast.Field) string {
+	var texts []string
+	for _, field := range fields {
+		if len(field.Names) == 0 || field.Tag == nil {
+			continue
+		}
+		blank := true
+		for _, ident := range field.Names {
+			if ident.Name != "_" {
+				blank = false
+			}
+		}
+		if blank {
+			texts = append(texts, fieldTagText(field))
+		}
+	}
+	return strings.Join(texts, " ")
+}
+
+// buildConstantName builds the identifier of a field constant of an element,
+// leaving the struct name out when output.format.omit_struct is set
+func (b *modelBuilder) buildConstantName(el *ConfigTag, packageName string, structName string, fieldPart string) string {
+	if el.Output.Format.isOmitStruct() {
+		structName = ""
 	}
+	return b.buildElementName(el, packageName, structName, fieldPart)
 }
 
-// buildName builds a Go identifier from parts using a format
-func (b *modelBuilder) buildName(prefix string, mid string, mid2 string, suffix string, fmtType ConstantFormatType) string {
+// buildElementName builds the identifier of an element output from the element
+// prefix, suffix and struct format, led by the package name when
+// format.include_package is set
+func (b *modelBuilder) buildElementName(el *ConfigTag, packageName string, mid string, mid2 string) string {
+	format := el.Output.Format
+	// Aggregated constants are qualified by the package they come from
+	includePackage := format.isIncludePackage() || !isStringBlank(el.Output.AggregateTo)
+	if !includePackage || packageName == "" {
+		return b.buildName(format.Prefix, mid, mid2, format.Suffix, format.Struct, format.isPrefixLiteral())
+	}
+	if !format.isPrefixLiteral() {
+		return b.buildName(packageName+" "+format.Prefix, mid, mid2, format.Suffix, format.Struct, false)
+	}
+	// The package goes before the literal prefix, formatted on its own
+	name := b.buildName(format.Prefix, mid, mid2, format.Suffix, format.Struct, true)
+	packagePart := b.buildName("", packageName, "", "", format.Struct, false)
+	if format.Struct == ConstantFormatSnake || format.Struct == ConstantFormatSnakeUpper {
+		return packagePart + "_" + name
+	}
+	return packagePart + name
+}
+
+// buildName builds a Go identifier from parts using a format. Only the middle
+// parts are formatted, the suffix keeps its casing and so does the prefix when
+// literalPrefix is set; otherwise the prefix is formatted with the middle parts.
+func (b *modelBuilder) buildName(prefix string, mid string, mid2 string, suffix string, fmtType ConstantFormatType, literalPrefix bool) string {
 	var parts []string
-	if prefix != "" {
+	if prefix != "" && !literalPrefix {
 		parts = append(parts, prefix)
 	}
 	if mid != "" {
@@ -510,22 +2033,46 @@ func (b *modelBuilder) buildName(prefix string, mid string, mid2 string, suffix
 	if mid2 != "" {
 		parts = append(parts, mid2)
 	}
-	if suffix != "" {
-		parts = append(parts, suffix)
-	}
 	base := strings.Join(parts, " ")
+
+	// Camel only lowers the first word, which is the literal prefix when set
+	if fmtType == ConstantFormatCamel && prefix != "" && literalPrefix {
+		fmtType = ConstantFormatPascal
+	}
+
+	var formatted string
 	switch fmtType {
 	case ConstantFormatCamel:
-		return toCamelCase(base)
+		formatted = toCamelCase(base)
 	case ConstantFormatPascal:
-		return toPascalCase(base)
+		formatted = toPascalCase(base)
 	case ConstantFormatSnake:
-		return strings.ToLower(strings.Join(splitIntoWords(base), "_"))
+		formatted = strings.ToLower(strings.Join(splitIntoWords(base), "_"))
 	case ConstantFormatSnakeUpper:
-		return strings.ToUpper(strings.Join(splitIntoWords(base), "_"))
+		formatted = strings.ToUpper(strings.Join(splitIntoWords(base), "_"))
 	default:
-		return toPascalCase(base)
+		formatted = toPascalCase(base)
+	}
+
+	var pieces []string
+	if prefix != "" && literalPrefix {
+		pieces = append(pieces, prefix)
+	}
+	if formatted != "" {
+		pieces = append(pieces, formatted)
+	}
+	if fmtType == ConstantFormatSnake || fmtType == ConstantFormatSnakeUpper {
+		if suffix != "" {
+			pieces = append(pieces, suffix)
+		}
+		return strings.Join(pieces, "_")
+	}
+	// The suffix starts a new word, so only its first letter is capitalized,
+	// e.g. key gives JsonUserNameKey and ID gives JsonUserNameID
+	if suffix != "" {
+		pieces = append(pieces, capitalizeFirst(suffix))
 	}
+	return strings.Join(pieces, "")
 }
 
 // transformFieldValue applies case and separator rules
@@ -545,6 +2092,10 @@ func transformFieldValue(value string, caseType TransformCaseType, sep string) s
 		value = strings.ToUpper(value)
 	case TransformCaseLower:
 		value = strings.ToLower(value)
+	default:
+		if fn, ok := customTransform(caseType); ok {
+			value = fn(value)
+		}
 	}
 	if sep != "" {
 		value = strings.Join(splitIntoWords(value), sep)
@@ -552,12 +2103,468 @@ func transformFieldValue(value string, caseType TransformCaseType, sep string) s
 	return value
 }
 
+// typeSuffixName returns the name of a declared type used as an identifier
+// suffix, taking the base name of qualified types, e.g. Time for *time.Time
+// and String Slice for []string. Unnamed types like structs give "".
+func typeSuffixName(goType string) string {
+	goType = strings.TrimLeft(goType, "*")
+	switch {
+	case strings.HasPrefix(goType, "map["):
+		return "Map"
+	case strings.HasPrefix(goType, "["):
+		if i := strings.Index(goType, "]"); i > 0 {
+			if elem := typeSuffixName(goType[i+1:]); elem != "" {
+				return elem + " Slice"
+			}
+		}
+		return ""
+	}
+	// Type arguments of generic types aren't part of the name
+	if i := strings.Index(goType, "["); i > 0 {
+		goType = goType[:i]
+	}
+	if i := strings.LastIndex(goType, "."); i >= 0 {
+		goType = goType[i+1:]
+	}
+	if !isValidGoIdentifier(goType) {
+		return ""
+	}
+	return goType
+}
+
+// fieldGroup returns the category of a field read from the output.group_by_tag
+// tag, or "" when the option or the tag isn't set
+func (b *modelBuilder) fieldGroup(tagText string) string {
+	key := strings.TrimSpace(b.config.Output.GroupByTag)
+	if key == "" {
+		return ""
+	}
+	value, _ := b.lookupTag(parseStructTags(tagText), key)
+	return strings.TrimSpace(value)
+}
+
+// groupConstants partitions the constants by group, keeping the order of
+// their first appearance and leaving the ungrouped ones first, and marks
+// the first constant of each group so a comment can introduce it
+func groupConstants(constants []*ConstantOutput) []*ConstantOutput {
+	var groups []string
+	byGroup := map[string][]*ConstantOutput{}
+	for _, c := range constants {
+		if _, ok := byGroup[c.Group]; !ok && c.Group != "" {
+			groups = append(groups, c.Group)
+		}
+		byGroup[c.Group] = append(byGroup[c.Group], c)
+	}
+
+	grouped := make([]*ConstantOutput, 0, len(constants))
+	grouped = append(grouped, byGroup[""]...)
+	for _, group := range groups {
+		byGroup[group][0].GroupStart = true
+		grouped = append(grouped, byGroup[group]...)
+	}
+	return grouped
+}
+
+// structFieldIndexes returns the index of every named field as given by
+// reflect.Type.Field, counting all the fields in source order, whether
+// included or not, so embedded and blank fields take an index too
+func structFieldIndexes(fields []*ast.Field) map[string]int {
+	indexes := map[string]int{}
+	index := 0
+	for _, field := range fields {
+		if len(field.Names) == 0 {
+			index++
+			continue
+		}
+		for _, ident := range field.Names {
+			indexes[ident.Name] = index
+			index++
+		}
+	}
+	return indexes
+}
+
+// structSourceHash hashes the names, types and tags of the struct fields, in
+// source order, so changes of the declaration can be detected without scanning
+func structSourceHash(structType *ast.StructType) string {
+	hash := sha256.New()
+	for _, field := range structType.Fields.List {
+		for _, ident := range field.Names {
+			hash.Write([]byte(ident.Name + " "))
+		}
+		hash.Write([]byte(types.ExprString(field.Type) + " " + fieldTagText(field) + "\n"))
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// flattenEmbedded returns the fields followed, in place of each embedded
+// struct declared in the same file, by the fields it promotes, whether it's
+// embedded by value or by pointer. As in Go, a promoted field is shadowed by
+// a field of the same name at a shallower depth, and fields of the same name
+// at the same depth aren't promoted. Visiting holds the structs being
+// flattened, so recursive embeds stop.
+func flattenEmbedded(fields []*ast.Field, localTypes map[string]ast.Expr, visiting map[string]bool) []*ast.Field {
+	promoted := promotedFields(fields, localTypes, visiting, 1)
+	if len(promoted) == 0 {
+		return fields
+	}
+
+	// Depth of the shallowest fields by name, and how many there are
+	depths := map[string]int{}
+	counts := map[string]int{}
+	for _, field := range fields {
+		for _, ident := range field.Names {
+			depths[ident.Name] = 0
+			counts[ident.Name] = 1
+		}
+	}
+	for _, p := range promoted {
+		depth, ok := depths[p.name.Name]
+		switch {
+		case !ok || p.depth < depth:
+			depths[p.name.Name] = p.depth
+			counts[p.name.Name] = 1
+		case p.depth == depth:
+			counts[p.name.Name]++
+		}
+	}
+
+	var flattened []*ast.Field
+	next := 0
+	for _, field := range fields {
+		flattened = append(flattened, field)
+		if len(field.Names) > 0 {
+			continue
+		}
+		for ; next < len(promoted) && promoted[next].embedded == field; next++ {
+			p := promoted[next]
+			if depths[p.name.Name] == p.depth && counts[p.name.Name] == 1 {
+				flattened = append(flattened, &ast.Field{Doc: p.field.Doc, Names: []*ast.Ident{p.name}, Type: p.field.Type, Tag: p.field.Tag, Comment: p.field.Comment})
+			}
+		}
+	}
+	return flattened
+}
+
+// promotedField is a field reachable through an embedded struct
+type promotedField struct {
+	field *ast.Field
+	name  *ast.Ident
+	depth int
+
+	// Embedded field of the flattened struct the field is reached through
+	embedded *ast.Field
+}
+
+// promotedFields returns the fields promoted by the embedded structs of
+// fields, from the depth down, in declaration order
+func promotedFields(fields []*ast.Field, localTypes map[string]ast.Expr, visiting map[string]bool, depth int) []promotedField {
+	var promoted []promotedField
+	for _, field := range fields {
+		if len(field.Names) > 0 {
+			continue
+		}
+		typeExpr := field.Type
+		if star, ok := typeExpr.(*ast.StarExpr); ok {
+			typeExpr = star.X
+		}
+		ident, ok := typeExpr.(*ast.Ident)
+		if !ok || visiting[ident.Name] {
+			continue
+		}
+		embedded, ok := localTypes[ident.Name].(*ast.StructType)
+		if !ok {
+			continue
+		}
+
+		// Fields promoted by nested embeds go in place of them
+		visiting[ident.Name] = true
+		for _, f := range embedded.Fields.List {
+			for _, name := range f.Names {
+				promoted = append(promoted, promotedField{field: f, name: name, depth: depth, embedded: field})
+			}
+			if len(f.Names) > 0 {
+				continue
+			}
+			for _, p := range promotedFields([]*ast.Field{f}, localTypes, visiting, depth+1) {
+				p.embedded = field
+				promoted = append(promoted, p)
+			}
+		}
+		delete(visiting, ident.Name)
+	}
+	return promoted
+}
+
+// fileTypeDecls returns the types declared in a file by name
+func fileTypeDecls(node *ast.File) map[string]ast.Expr {
+	types := map[string]ast.Expr{}
+	for _, decl := range node.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok || genDecl.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range genDecl.Specs {
+			if typeSpec, ok := spec.(*ast.TypeSpec); ok {
+				types[typeSpec.Name.Name] = typeSpec.Type
+			}
+		}
+	}
+	return types
+}
+
+// packageTypeDecls returns the types declared by the package of the file by
+// name, taken from the non-test files of its directory declaring the same
+// package. The declarations of the file itself win, since test files are
+// scanned too.
+func (b *modelBuilder) packageTypeDecls(filePath string, node *ast.File) map[string]ast.Expr {
+	fileTypes := maps.Clone(b.dirTypeDecls(filepath.Dir(filePath), node.Name.Name))
+	maps.Copy(fileTypes, fileTypeDecls(node))
+	return fileTypes
+}
+
+// dirTypeDecls returns the types declared by the package named packageName in
+// the non-test files of dir, parsed once per package
+func (b *modelBuilder) dirTypeDecls(dir string, packageName string) map[string]ast.Expr {
+	key := dir + ":" + packageName
+	if b.packageTypes == nil {
+		b.packageTypes = map[string]map[string]ast.Expr{}
+	}
+	types, ok := b.packageTypes[key]
+	if !ok {
+		types = map[string]ast.Expr{}
+		entries, _ := os.ReadDir(dir)
+		for _, entry := range entries {
+			name := entry.Name()
+			if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+				continue
+			}
+			sibling, err := parser.ParseFile(token.NewFileSet(), filepath.Join(dir, name), nil, parser.SkipObjectResolution)
+			// best effort; skip invalid files
+			if err != nil || sibling.Name.Name != packageName {
+				continue
+			}
+			maps.Copy(types, fileTypeDecls(sibling))
+		}
+		b.packageTypes[key] = types
+	}
+	return types
+}
+
+// jsonTypeOf maps a Go type to the JSON type it's encoded as by encoding/json,
+// following the types declared in the package. Types that can't be encoded,
+// whose encoding depends on the value, like interfaces, or that can't be
+// resolved, like named types of other packages, map to "".
+func jsonTypeOf(expr ast.Expr, packageTypes map[string]ast.Expr, depth int) string {
+	switch t := expr.(type) {
+	case *ast.ParenExpr:
+		return jsonTypeOf(t.X, packageTypes, depth)
+	case *ast.StarExpr:
+		return jsonTypeOf(t.X, packageTypes, depth)
+	case *ast.ArrayType:
+		// Byte slices are encoded as base64 strings
+		if ident, ok := t.Elt.(*ast.Ident); ok && t.Len == nil && (ident.Name == "byte" || ident.Name == "uint8") {
+			return "string"
+		}
+		return "array"
+	case *ast.MapType, *ast.StructType:
+		return "object"
+	case *ast.Ident:
+		switch t.Name {
+		case "string":
+			return "string"
+		case "bool":
+			return "boolean"
+		case "int", "int8", "int16", "int32", "int64",
+			"uint", "uint8", "uint16", "uint32", "uint64", "uintptr",
+			"float32", "float64", "byte", "rune":
+			return "number"
+		case "any", "error", "complex64", "complex128":
+			return ""
+		}
+		// Named types declared in the package are followed, bounding the
+		// depth in case of invalid recursive declarations
+		if underlying, ok := packageTypes[t.Name]; ok && depth < 10 {
+			return jsonTypeOf(underlying, packageTypes, depth+1)
+		}
+		return ""
+	case *ast.SelectorExpr:
+		// time.Time implements encoding.TextMarshaler
+		if pkg, ok := t.X.(*ast.Ident); ok && pkg.Name == "time" && t.Sel.Name == "Time" {
+			return "string"
+		}
+		return ""
+	case *ast.IndexExpr:
+		// Instances of generic types follow the generic type
+		return jsonTypeOf(t.X, packageTypes, depth)
+	case *ast.IndexListExpr:
+		return jsonTypeOf(t.X, packageTypes, depth)
+	default:
+		// Interfaces, funcs and channels
+		return ""
+	}
+}
+
+// tagOptions returns the options of the tag the element value is taken from,
+// e.g. omitempty for json:"name,omitempty", or an empty string when the value
+// doesn't come from a tag with options
+func tagOptions(matched *tagMatch, el *ConfigTag) string {
+	if matched == nil || el.Input.TagSyntax != TagSyntaxStandard {
+		return ""
+	}
+	name := tagValueName(matched.value)
+	if name == matched.value {
+		return ""
+	}
+	return matched.value[len(name)+1:]
+}
+
+// constantComment returns the comment of a constant with output.emit_source_tag
+// and output.annotate_options, e.g. "json tag (omitempty)"
+func (b *modelBuilder) constantComment(matched *tagMatch, el *ConfigTag) string {
+	options := ""
+	if b.config.Output.isAnnotateOptions() {
+		options = tagOptions(matched, el)
+	}
+	if !b.config.Output.isEmitSourceTag() || matched == nil {
+		return options
+	}
+	if options == "" {
+		return matched.key + " tag"
+	}
+	return matched.key + " tag (" + options + ")"
+}
+
 // Tag helpers
+// tagValueName returns the tag value up to the first comma that isn't inside
+// single quotes, so quoted options like validate:"oneof='a,b'" are kept whole
+func tagValueName(value string) string {
+	return splitTagOptions(value)[0]
+}
+
+// splitTagOptions splits a tag value on the commas that aren't inside single
+// quotes, e.g. required and oneof='a,b' c for validate:"required,oneof='a,b' c"
+func splitTagOptions(value string) []string {
+	var options []string
+	quoted := false
+	start := 0
+	for i, r := range value {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+		case r == ',' && !quoted:
+			options = append(options, value[start:i])
+			start = i + 1
+		}
+	}
+	return append(options, value[start:])
+}
+
+// elementTagValue extracts the element value from a raw tag value following
+// the element tag syntax. Standard values are taken up to the first comma
+// (e.g. json:"name,omitempty"), and keyvalue ones from the option_key option
+// (e.g. gorm:"column:user_name;size:64"), reporting false when it's missing.
+// Standard gorm values only name the column with the column option, since
+// bare ones like gorm:"primaryKey" are settings, and sql and db values give
+// their column option when they have one, so db:"user_name" and
+// gorm:"column:user_name" both give user_name. Protobuf values come from the
+// name option of the field descriptor (e.g. protobuf:"bytes,1,opt,name=user_name,proto3").
+func elementTagValue(key string, value string, el *ConfigTag) (string, bool) {
+	if el.Input.TagSyntax == TagSyntaxProtobuf {
+		for _, option := range strings.Split(value, ",") {
+			if name, ok := strings.CutPrefix(strings.TrimSpace(option), "name="); ok && name != "" {
+				return name, true
+			}
+		}
+		return "", false
+	}
+
+	if el.Input.TagSyntax == TagSyntaxKeyValue {
+		return keyValueOption(value, el.Input.OptionKey)
+	}
+	switch strings.ToLower(key) {
+	case "gorm":
+		return keyValueOption(value, "column")
+	case "sql", "db":
+		if column, ok := keyValueOption(value, "column"); ok {
+			return column, true
+		}
+	}
+	return tagValueName(value), true
+}
+
+// keyValueOption returns the value of an option of a tag written as
+// key:value options separated by semicolons, e.g. user_name for the column
+// option of gorm:"column:user_name;size:64", or false when it's missing
+func keyValueOption(value string, optionKey string) (string, bool) {
+	for _, option := range strings.Split(value, ";") {
+		k, v, found := strings.Cut(option, ":")
+		if found && strings.EqualFold(strings.TrimSpace(k), optionKey) {
+			return strings.TrimSpace(v), true
+		}
+	}
+	return "", false
+}
+
+// oneofValues returns the values allowed by the oneof option of the first
+// element tag declaring it, e.g. a, b and c for validate:"required,oneof=a b c".
+// Values holding spaces are quoted, as in oneof='in progress' done.
+func (b *modelBuilder) oneofValues(tagText string, el *ConfigTag) []string {
+	tags := parseStructTags(tagText)
+	for _, key := range el.Input.TagPriority {
+		value, ok := b.lookupTag(tags, key)
+		if !ok {
+			continue
+		}
+		for _, option := range splitTagOptions(value) {
+			list, found := strings.CutPrefix(strings.TrimSpace(option), "oneof=")
+			if !found {
+				continue
+			}
+			var values []string
+			for list = strings.TrimSpace(list); list != ""; list = strings.TrimSpace(list) {
+				if rest, quoted := strings.CutPrefix(list, "'"); quoted {
+					if end := strings.Index(rest, "'"); end >= 0 {
+						values = append(values, rest[:end])
+						list = rest[end+1:]
+						continue
+					}
+				}
+				word, rest, _ := strings.Cut(list, " ")
+				values = append(values, word)
+				list = rest
+			}
+			return values
+		}
+	}
+	return nil
+}
+
+// fieldTagText returns the tag of a field without quotes, handling tags
+// written as raw or interpreted strings
+func fieldTagText(field *ast.Field) string {
+	if field.Tag == nil {
+		return ""
+	}
+	if text, err := strconv.Unquote(field.Tag.Value); err == nil {
+		return text
+	}
+	return strings.Trim(field.Tag.Value, "`")
+}
+
 // parseStructTags converts a raw tag string to reflect.StructTag
 func parseStructTags(tagString string) reflect.StructTag {
 	return reflect.StructTag(tagString)
 }
 
+// lookupTag looks up a tag key honoring input.tag_case_insensitive
+func (b *modelBuilder) lookupTag(tags reflect.StructTag, key string) (string, bool) {
+	if b.config.Input.isTagCaseInsensitive() {
+		return lookupTagFold(tags, key)
+	}
+	return lookupTag(tags, key)
+}
+
 func lookupTag(tags reflect.StructTag, key string) (string, bool) {
 	v := tags.Get(key)
 	if strings.TrimSpace(v) == "" {
@@ -566,6 +2573,57 @@ func lookupTag(tags reflect.StructTag, key string) (string, bool) {
 	return v, true
 }
 
+// lookupTagFold is like lookupTag but matches keys ignoring case. It scans the
+// tag manually since reflect.StructTag.Get is case-sensitive.
+func lookupTagFold(tags reflect.StructTag, key string) (string, bool) {
+	tag := string(tags)
+	for tag != "" {
+		// Skip leading space
+		i := 0
+		for i < len(tag) && tag[i] == ' ' {
+			i++
+		}
+		tag = tag[i:]
+		if tag == "" {
+			break
+		}
+
+		// Scan to colon. A space, a quote or a control character is a syntax error.
+		i = 0
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != 0x7f {
+			i++
+		}
+		if i == 0 || i+1 >= len(tag) || tag[i] != ':' || tag[i+1] != '"' {
+			break
+		}
+		name := tag[:i]
+		tag = tag[i+1:]
+
+		// Scan quoted string to find value
+		i = 1
+		for i < len(tag) && tag[i] != '"' {
+			if tag[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(tag) {
+			break
+		}
+		quoted := tag[:i+1]
+		tag = tag[i+1:]
+
+		if strings.EqualFold(name, key) {
+			value, err := strconv.Unquote(quoted)
+			if err != nil || strings.TrimSpace(value) == "" {
+				return "", false
+			}
+			return value, true
+		}
+	}
+	return "", false
+}
+
 // createValueOutput creates a ValueOutput from an AST field
 func (b *modelBuilder) createValueOutput(field *ast.Field, fieldName string, packageName string, importIndex map[string]*TypePackageOutput, modulePath string, moduleDir string) *ValueOutput {
 	if field.Type == nil {
@@ -609,8 +2667,13 @@ func (b *modelBuilder) createValueOutput(field *ast.Field, fieldName string, pac
 							// try to get the actual package name from go list or infer from path
 							name := imp.Name
 							if strings.Contains(name, ".") {
-								// The name looks like an identifier (e.g., "yaml.v3"), try to get the real package name
-								if realPkgName := getPackageNameFromGoList(imp.Path, moduleDir); realPkgName != "" {
+								// The name looks like an identifier (e.g., "yaml.v3"), try to get the real package name.
+								// A missing toolchain was already reported while building the import index.
+								realPkgName := ""
+								if b.mustResolveTypes() {
+									realPkgName, _ = b.resolvePackageName(imp.Path, moduleDir)
+								}
+								if realPkgName != "" {
 									name = realPkgName
 								} else {
 									// Fallback: for patterns like gopkg.in/yaml.v3, the package name is usually the part before the dot
@@ -652,6 +2715,12 @@ func (b *modelBuilder) createValueOutput(field *ast.Field, fieldName string, pac
 	return valueOutput
 }
 
+// isCollectionType reports whether a field type is a slice, array or map
+func (b *modelBuilder) isCollectionType(expr ast.Expr) bool {
+	typeName, _ := b.extractTypeInfo(expr, nil, "")
+	return strings.HasPrefix(typeName, "[]") || strings.HasPrefix(typeName, "map[")
+}
+
 // extractTypeInfo extracts type name and package info from an AST expression
 func (b *modelBuilder) extractTypeInfo(expr ast.Expr, importIndex map[string]*TypePackageOutput, modulePath string) (typeName string, pkg *TypePackageOutput) {
 	switch t := expr.(type) {
@@ -670,7 +2739,7 @@ func (b *modelBuilder) extractTypeInfo(expr ast.Expr, importIndex map[string]*Ty
 					return t.Sel.Name, &TypePackageOutput{Path: imp.Path, Name: imp.Name}
 				} else {
 					// External package - return qualified name
-					return ident.Name + "." + t.Sel.Name, &TypePackageOutput{Path: imp.Path, Name: imp.Name}
+					return ident.Name + "." + t.Sel.Name, &TypePackageOutput{Path: imp.Path, Name: imp.Name, Alias: imp.Alias}
 				}
 			}
 			// Secondary lookup: find any import whose real Name matches the ident
@@ -758,7 +2827,7 @@ func (b *modelBuilder) extractTypeInfo(expr ast.Expr, importIndex map[string]*Ty
 }
 
 // buildImportIndex indexes file imports by the identifier used in code (alias or default name)
-func (b *modelBuilder) buildImportIndex(node *ast.File, currentFilePath string) (map[string]*TypePackageOutput, string) {
+func (b *modelBuilder) buildImportIndex(node *ast.File, currentFilePath string) (map[string]*TypePackageOutput, string, error) {
 	idx := make(map[string]*TypePackageOutput)
 	// Try to locate module directory and module path (from go.mod)
 	moduleDir, modulePath := locateGoModule(currentFilePath)
@@ -772,7 +2841,7 @@ func (b *modelBuilder) buildImportIndex(node *ast.File, currentFilePath string)
 		} else {
 			// For external packages, Go uses the package name from the module's go.mod
 			// For local packages, derive from the last segment of the import path
-			if moduleDir != "" && modulePath != "" && strings.HasPrefix(path, modulePath) {
+			if moduleDir != "" && isModulePackage(path, modulePath) {
 				// Local package - derive from path
 				if i := strings.LastIndex(path, "/"); i >= 0 {
 					ident = path[i+1:]
@@ -797,7 +2866,7 @@ func (b *modelBuilder) buildImportIndex(node *ast.File, currentFilePath string)
 
 		// Try to read the actual package name from source files
 		realName := ident // Default to the identifier
-		if moduleDir != "" && modulePath != "" && strings.HasPrefix(path, modulePath) {
+		if moduleDir != "" && isModulePackage(path, modulePath) {
 			// Local package - read from local directory
 			rel := strings.TrimPrefix(path, modulePath)
 			rel = strings.TrimPrefix(rel, "/")
@@ -808,7 +2877,15 @@ func (b *modelBuilder) buildImportIndex(node *ast.File, currentFilePath string)
 		} else {
 			// External package - use go list to get the actual package name
 			// This is the most reliable way to get the package name
-			if pkgName := getPackageNameFromGoList(path, moduleDir); pkgName != "" {
+			pkgName := ""
+			if b.mustResolveTypes() {
+				name, err := b.resolvePackageName(path, moduleDir)
+				if err != nil {
+					return nil, "", err
+				}
+				pkgName = name
+			}
+			if pkgName != "" {
 				realName = pkgName
 			} else if pkgName := readPackageNameFromImportPath(path); pkgName != "" {
 				// Fallback: try to read from module cache
@@ -818,7 +2895,13 @@ func (b *modelBuilder) buildImportIndex(node *ast.File, currentFilePath string)
 
 		// Store under both the identifier used in code and the real package name
 		// (helps when selector uses real name and import used alias, or vice versa)
-		idx[ident] = &TypePackageOutput{Path: path, Name: realName}
+		// An explicit alias differing from the package name is kept, so the
+		// generated import matches the type references taken from the source
+		entry := &TypePackageOutput{Path: path, Name: realName}
+		if imp.Name != nil && ident != realName {
+			entry.Alias = ident
+		}
+		idx[ident] = entry
 		if ident != realName {
 			// Always store/update the entry under realName with the path to ensure it's available
 			// This ensures lookups by package name can find the correct import path
@@ -828,7 +2911,17 @@ func (b *modelBuilder) buildImportIndex(node *ast.File, currentFilePath string)
 			}
 		}
 	}
-	return idx, modulePath
+	return idx, modulePath, nil
+}
+
+// isModulePackage reports whether the import path is a package of the module,
+// so example.com/app/model belongs to example.com/app but example.com/appkit
+// doesn't, which matters when several modules share a root
+func isModulePackage(importPath string, modulePath string) bool {
+	if modulePath == "" {
+		return false
+	}
+	return importPath == modulePath || strings.HasPrefix(importPath, modulePath+"/")
 }
 
 // locateGoModule walks up from the current file to find a go.mod and returns (moduleDir, modulePath)
@@ -846,7 +2939,11 @@ func locateGoModule(currentFilePath string) (string, string) {
 			for _, l := range lines {
 				l = strings.TrimSpace(l)
 				if strings.HasPrefix(l, "module ") {
-					return dir, strings.TrimSpace(strings.TrimPrefix(l, "module "))
+					modulePath := strings.TrimPrefix(l, "module ")
+					if i := strings.Index(modulePath, "//"); i >= 0 {
+						modulePath = modulePath[:i]
+					}
+					return dir, strings.Trim(strings.TrimSpace(modulePath), "\"`")
 				}
 			}
 			return dir, ""
@@ -901,9 +2998,27 @@ func importPathHasSegment(path string, seg string) bool {
 	return false
 }
 
+// mustResolveTypes reports whether external package names must be resolved
+// with the go toolchain, which is only needed by getters returning :value
+func (b *modelBuilder) mustResolveTypes() bool {
+	if !b.config.Input.isResolveTypes() {
+		return false
+	}
+	for _, g := range b.config.Getters {
+		for _, ret := range g.Returns {
+			if ret == ":value" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // getPackageNameFromGoList uses `go list` to get the actual package name for an import path.
 // This is the most reliable way to get the package name for external packages.
-func getPackageNameFromGoList(importPath string, moduleDir string) string {
+// An error is only returned when the go toolchain can't be found, any other
+// failure returns an empty name so the caller can fall back to other strategies.
+func getPackageNameFromGoList(importPath string, moduleDir string) (string, error) {
 	// Use go list to get the package name
 	// This works for any import path, including versioned modules
 	cmd := exec.Command("go", "list", "-f", "{{.Name}}", importPath)
@@ -912,13 +3027,16 @@ func getPackageNameFromGoList(importPath string, moduleDir string) string {
 	}
 	output, err := cmd.Output()
 	if err != nil {
-		return ""
+		if errors.Is(err, exec.ErrNotFound) {
+			return "", fmt.Errorf("failed to resolve package name of %q: the go toolchain was not found in PATH, install Go or set input.resolve_types to false: %w", importPath, err)
+		}
+		return "", nil
 	}
 	name := strings.TrimSpace(string(output))
 	if name != "" && name != "main" {
-		return name
+		return name, nil
 	}
-	return ""
+	return "", nil
 }
 
 // readPackageNameFromImportPath attempts to read the actual package name from an external import path
@@ -1526,7 +1526,7 @@ type Admin struct {
 								TypePackage: &TypePackageOutput{
 									Path:  "github.com/example/booleans",
 									Name:  "booleans",
-									Alias: "",
+									Alias: "binary",
 								},
 							},
 						},
@@ -1818,3 +1818,2751 @@ type User struct {
 		})
 	}
 }
+
+func TestModelBuilderBuildConstantsWithCollectionSuffix(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type User struct {
+	Name string ` + "`json:\"name\"`" + `
+	Tags []string ` + "`json:\"tags\"`" + `
+	Meta map[string]string ` + "`json:\"meta\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config, err := NewConfig(&Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "json",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTagThenField,
+					TagPriority: []string{"json"},
+				},
+				Output: ConfigTagOutput{
+					Mode:             OutputModeConstant,
+					CollectionSuffix: "List",
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	builder := NewModelBuilder(config)
+	require.NoError(t, builder.scanFile(testFile))
+
+	require.Len(t, builder.model.Packages[tempDir].Structs, 1)
+	constants := map[string]string{}
+	for _, c := range builder.model.Packages[tempDir].Structs[0].Constants {
+		constants[c.Name] = c.Value
+	}
+	assert.Equal(t, map[string]string{
+		"JsonUserName":     "name",
+		"JsonUserTagsList": "tags",
+		"JsonUserMetaList": "meta",
+	}, constants)
+}
+
+func TestModelBuilderBuildAnonymousStructAlias(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "point.go")
+	content := `package main
+
+type Point = struct {
+	X int ` + "`json:\"x\"`" + `
+	Y int ` + "`json:\"y\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	buildConfig := func(includeAnonymous bool) (*Config, error) {
+		return NewConfig(&Config{
+			Input: ConfigInput{
+				Dir: tempDir,
+				Struct: ConfigInputStruct{
+					IncludeAnonymous: boolPtr(includeAnonymous),
+				},
+			},
+			Elements: []ConfigTag{
+				{
+					Name: "json",
+					Input: ConfigTagInput{
+						Mode:        InputModeTypeTagThenField,
+						TagPriority: []string{"json"},
+					},
+				},
+			},
+			Getters: []ConfigGetter{
+				{
+					Name:    "Get",
+					Returns: []string{"json"},
+				},
+			},
+		})
+	}
+
+	t.Run("excluded by default", func(t *testing.T) {
+		config, err := buildConfig(false)
+		require.NoError(t, err)
+
+		builder := NewModelBuilder(config)
+		require.NoError(t, builder.scanFile(testFile))
+		assert.Len(t, builder.model.Packages, 0)
+	})
+
+	t.Run("included with include_anonymous", func(t *testing.T) {
+		config, err := buildConfig(true)
+		require.NoError(t, err)
+
+		builder := NewModelBuilder(config)
+		require.NoError(t, builder.scanFile(testFile))
+
+		require.Len(t, builder.model.Packages[tempDir].Structs, 1)
+		structModel := builder.model.Packages[tempDir].Structs[0]
+		assert.Equal(t, "Point", structModel.Name)
+
+		constants := map[string]string{}
+		for _, c := range structModel.Constants {
+			constants[c.Name] = c.Value
+		}
+		assert.Equal(t, map[string]string{"JsonPointX": "x", "JsonPointY": "y"}, constants)
+
+		// Methods can't be declared on aliases of anonymous structs
+		assert.Len(t, structModel.Getters, 0)
+	})
+}
+
+func TestModelBuilderMissingGoToolchain(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+import "github.com/example/ext"
+
+type User struct {
+	Name ext.Name ` + "`json:\"name\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	// Simulate a missing toolchain
+	t.Setenv("PATH", "")
+
+	buildConfig := func(resolveTypes bool) (*Config, error) {
+		return NewConfig(&Config{
+			Input: ConfigInput{
+				Dir:          tempDir,
+				ResolveTypes: boolPtr(resolveTypes),
+			},
+			Getters: []ConfigGetter{
+				{
+					Name:    "Value",
+					Returns: []string{":value"},
+				},
+			},
+		})
+	}
+
+	t.Run("clear error when resolving types", func(t *testing.T) {
+		config, err := buildConfig(true)
+		require.NoError(t, err)
+
+		err = NewModelBuilder(config).scanFile(testFile)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "go toolchain was not found")
+		assert.Contains(t, err.Error(), "input.resolve_types")
+	})
+
+	t.Run("fallback when type resolution is disabled", func(t *testing.T) {
+		config, err := buildConfig(false)
+		require.NoError(t, err)
+
+		builder := NewModelBuilder(config)
+		require.NoError(t, builder.scanFile(testFile))
+
+		require.Len(t, builder.model.Packages[tempDir].Structs, 1)
+		getters := builder.model.Packages[tempDir].Structs[0].Getters
+		require.Len(t, getters, 1)
+		assert.Equal(t, "ext.Name", getters[0].Returns[0].Value.TypeName)
+		assert.Equal(t, "github.com/example/ext", getters[0].Returns[0].Value.TypePackage.Path)
+	})
+}
+
+func TestModelBuilderBuildDroppedGetterImports(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+import (
+	"time"
+
+	"github.com/example/ext"
+)
+
+type User struct {
+	Name      ext.Name  ` + "`json:\"name\" title:\"Name\"`" + `
+	CreatedAt time.Time ` + "`json:\"created_at\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config, err := NewConfig(&Config{
+		Input: ConfigInput{
+			Dir:          tempDir,
+			ResolveTypes: boolPtr(false),
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "title",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTag,
+					TagPriority: []string{"title"},
+				},
+			},
+		},
+		Getters: []ConfigGetter{
+			{
+				Name:    "Titled",
+				Returns: []string{":value", "title"},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	builder := NewModelBuilder(config)
+	require.NoError(t, builder.scanFile(testFile))
+
+	pkg := builder.model.Packages[tempDir]
+	require.NotNil(t, pkg)
+	require.Len(t, pkg.Structs, 1)
+
+	// CreatedAt has no title, so its getter is dropped along with its import
+	require.Len(t, pkg.Structs[0].Getters, 1)
+	assert.Equal(t, "ext.Name", pkg.Structs[0].Getters[0].Returns[0].Value.TypeName)
+	assert.Contains(t, pkg.Imports, "github.com/example/ext")
+	assert.NotContains(t, pkg.Imports, "time")
+}
+
+func TestModelBuilderBuildElementNamePrefixLiteral(t *testing.T) {
+	tests := []struct {
+		name          string
+		format        ConstantFormatType
+		prefixLiteral bool
+		expected      string
+	}{
+		{name: "pascal re-cased prefix", format: ConstantFormatPascal, prefixLiteral: false, expected: "DbUserName"},
+		{name: "pascal literal prefix", format: ConstantFormatPascal, prefixLiteral: true, expected: "DBUserName"},
+		{name: "camel literal prefix", format: ConstantFormatCamel, prefixLiteral: true, expected: "DBUserName"},
+		{name: "snake literal prefix", format: ConstantFormatSnake, prefixLiteral: true, expected: "DB_user_name"},
+		{name: "snake upper literal prefix", format: ConstantFormatSnakeUpper, prefixLiteral: true, expected: "DB_USER_NAME"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := NewConfig(&Config{
+				Elements: []ConfigTag{
+					{
+						Name: "db",
+						Output: ConfigTagOutput{
+							Format: ConfigTagOutputFormat{
+								Struct:        tt.format,
+								Prefix:        "DB",
+								PrefixLiteral: boolPtr(tt.prefixLiteral),
+							},
+						},
+					},
+				},
+			})
+			require.NoError(t, err)
+
+			builder := NewModelBuilder(config)
+			assert.Equal(t, tt.expected, builder.buildElementName(&config.Elements[0], "", "User", "Name"))
+		})
+	}
+}
+
+func TestModelBuilderBuildName(t *testing.T) {
+	tests := []struct {
+		name          string
+		prefix        string
+		suffix        string
+		format        ConstantFormatType
+		literalPrefix bool
+		expected      string
+	}{
+		{name: "pascal literal prefix", prefix: "API", format: ConstantFormatPascal, literalPrefix: true, expected: "APIUserName"},
+		{name: "pascal literal suffix", prefix: "Json", suffix: "ID", format: ConstantFormatPascal, literalPrefix: true, expected: "JsonUserNameID"},
+		{name: "pascal formatted prefix", prefix: "API", format: ConstantFormatPascal, literalPrefix: false, expected: "ApiUserName"},
+		{name: "camel formatted prefix", prefix: "json", format: ConstantFormatCamel, literalPrefix: false, expected: "jsonUserName"},
+		{name: "camel literal prefix", prefix: "api", format: ConstantFormatCamel, literalPrefix: true, expected: "apiUserName"},
+		{name: "snake literal prefix and suffix", prefix: "API", suffix: "V2", format: ConstantFormatSnake, literalPrefix: true, expected: "API_user_name_V2"},
+		{name: "snake upper formatted prefix", prefix: "api", format: ConstantFormatSnakeUpper, literalPrefix: false, expected: "API_USER_NAME"},
+	}
+
+	builder := &modelBuilder{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, builder.buildName(tt.prefix, "User", "Name", tt.suffix, tt.format, tt.literalPrefix))
+		})
+	}
+}
+
+func TestModelBuilderBuildConstantsWithExplicitPrefix(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type User struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config, err := NewConfig(&Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "json",
+				Output: ConfigTagOutput{
+					Format: ConfigTagOutputFormat{
+						Prefix:        "API",
+						PrefixLiteral: boolPtr(true),
+					},
+				},
+			},
+			{
+				// Default prefix taken from the element name is formatted
+				Name: "title",
+			},
+			{
+				// Prefixes are formatted unless literal, and the suffix starts
+				// a new word
+				Name: "db",
+				Output: ConfigTagOutput{
+					Format: ConfigTagOutputFormat{
+						Prefix: "json",
+						Suffix: "key",
+					},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	builder := NewModelBuilder(config)
+	require.NoError(t, builder.scanFile(testFile))
+
+	require.Len(t, builder.model.Packages[tempDir].Structs, 1)
+	var names []string
+	for _, c := range builder.model.Packages[tempDir].Structs[0].Constants {
+		names = append(names, c.Name)
+	}
+	assert.Equal(t, []string{"APIUserName", "TitleUserName", "JsonUserNameKey"}, names)
+}
+
+func TestModelBuilderBuildGettersWithLowercasePrefix(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type User struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config, err := NewConfig(&Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Elements: []ConfigTag{
+			{Name: "json"},
+		},
+		Getters: []ConfigGetter{
+			{
+				Name:    "Json",
+				Returns: []string{"json"},
+				Output: ConfigGetterOutput{
+					Prefix: "get",
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	builder := NewModelBuilder(config)
+	require.NoError(t, builder.scanFile(testFile))
+
+	// A lowercase prefix doesn't unexport the getter
+	getters := builder.model.Packages[tempDir].Structs[0].Getters
+	require.Len(t, getters, 1)
+	assert.Equal(t, "GetName", getters[0].Name)
+}
+
+func TestModelBuilderFindFilesSkipsGeneratedFiles(t *testing.T) {
+	tempDir := t.TempDir()
+
+	for _, name := range []string{"user.go", "user.generated.go", "constago.gen.go"} {
+		content := "package main\n\ntype Holder struct {\n\tName string\n}\n"
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644))
+	}
+
+	config, err := NewConfig(&Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Output: ConfigOutput{
+			GeneratedSuffix: ".generated.go",
+		},
+	})
+	require.NoError(t, err)
+
+	b := NewModelBuilder(config)
+	files, err := b.findFiles()
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(tempDir, "user.go")}, files)
+}
+
+func TestModelBuilderIgnoresPreviouslyGeneratedFiles(t *testing.T) {
+	tempDir := t.TempDir()
+
+	userFile := filepath.Join(tempDir, "user.go")
+	require.NoError(t, os.WriteFile(userFile, []byte(`package main
+
+type User struct {
+	Name string `+"`json:\"name\"`"+`
+}
+`), 0644))
+
+	// A file generated by a previous run under another file name, declaring a holder struct
+	generatedFile := filepath.Join(tempDir, "old_constants.go")
+	require.NoError(t, os.WriteFile(generatedFile, []byte(`// Code generated by constago generator; DO NOT EDIT.
+// This file was produced from the scanning model and configuration.
+
+package main
+
+type JsonUser struct {
+	Name string
+}
+`), 0644))
+
+	config, err := NewConfig(&Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Output: ConfigOutput{
+			FileName: "constants_gen.go",
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "json",
+				Output: ConfigTagOutput{
+					Mode: OutputModeStruct,
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	model, err := NewModelBuilder(config).Build()
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, model.FilesScanned)
+	require.Len(t, model.Packages[tempDir].Structs, 1)
+	assert.Equal(t, "User", model.Packages[tempDir].Structs[0].Name)
+}
+
+func TestModelBuilderIgnoresGeneratedHolders(t *testing.T) {
+	userContent := `package main
+
+type User struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+`
+	newConfig := func(dir string) *Config {
+		return &Config{
+			Input: ConfigInput{
+				Dir: dir,
+			},
+			Output: ConfigOutput{
+				FileName: "constants_gen.go",
+			},
+			Elements: []ConfigTag{
+				{
+					Name: "json",
+					Output: ConfigTagOutput{
+						Mode: OutputModeStruct,
+					},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		holders func(t *testing.T, dir string) string
+	}{
+		{
+			name: "previous output without its header",
+			holders: func(t *testing.T, dir string) string {
+				require.NoError(t, Generate(newConfig(dir)))
+				generatedFile := filepath.Join(dir, "constants_gen.go")
+				generated, err := os.ReadFile(generatedFile)
+				require.NoError(t, err)
+				require.NoError(t, os.Remove(generatedFile))
+				return strings.Replace(string(generated), generatedHeader, "// Hand-edited holders", 1)
+			},
+		},
+		{
+			name: "holder declared as a named type",
+			holders: func(t *testing.T, dir string) string {
+				return `package main
+
+type JsonUser struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+`
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(tempDir, "user.go"), []byte(userContent), 0644))
+			holders := tt.holders(t, tempDir)
+			require.NotContains(t, holders, generatedHeader)
+			require.NoError(t, os.WriteFile(filepath.Join(tempDir, "holders.go"), []byte(holders), 0644))
+
+			config, err := NewConfig(newConfig(tempDir))
+			require.NoError(t, err)
+			model, err := NewModelBuilder(config).Build()
+			require.NoError(t, err)
+
+			// Only User is kept, with its JsonUser holder
+			require.Len(t, model.Packages[tempDir].Structs, 1)
+			assert.Equal(t, "User", model.Packages[tempDir].Structs[0].Name)
+			require.Len(t, model.Packages[tempDir].Structs[0].Structs, 1)
+			assert.Equal(t, "JsonUser", model.Packages[tempDir].Structs[0].Structs[0].Name)
+		})
+	}
+}
+
+func TestModelBuilderBuildConstantsFieldOrder(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type User struct {
+	Name    string ` + "`json:\"b_name\"`" + `
+	Country string ` + "`json:\"c_country\"`" + `
+	Age     int    ` + "`json:\"a_age\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	tests := []struct {
+		order    FieldOrderType
+		expected []string
+	}{
+		{order: FieldOrderSource, expected: []string{"b_name", "c_country", "a_age"}},
+		{order: FieldOrderAlphabetical, expected: []string{"a_age", "c_country", "b_name"}},
+		{order: FieldOrderTag, expected: []string{"a_age", "b_name", "c_country"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.order), func(t *testing.T) {
+			config, err := NewConfig(&Config{
+				Input: ConfigInput{
+					Dir: tempDir,
+				},
+				Output: ConfigOutput{
+					FieldOrder: tt.order,
+				},
+				Elements: []ConfigTag{
+					{
+						Name: "json",
+						Input: ConfigTagInput{
+							Mode:        InputModeTypeTag,
+							TagPriority: []string{"json"},
+						},
+					},
+				},
+			})
+			require.NoError(t, err)
+
+			builder := NewModelBuilder(config)
+			require.NoError(t, builder.scanFile(testFile))
+
+			var values []string
+			for _, c := range builder.model.Packages[tempDir].Structs[0].Constants {
+				values = append(values, c.Value)
+			}
+			assert.Equal(t, tt.expected, values)
+		})
+	}
+}
+
+func TestModelBuilderBuildConstantsAndStructFieldOrder(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type User struct {
+	Name    string ` + "`json:\"b_name\" title:\"Name\"`" + `
+	Country string ` + "`json:\"c_country\" title:\"Country\"`" + `
+	Age     int    ` + "`json:\"a_age\" title:\"Age\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	tests := []struct {
+		order    FieldOrderType
+		expected []string
+	}{
+		{order: FieldOrderSource, expected: []string{"Name", "Country", "Age"}},
+		{order: FieldOrderAlphabetical, expected: []string{"Age", "Country", "Name"}},
+		{order: FieldOrderTag, expected: []string{"Age", "Name", "Country"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.order), func(t *testing.T) {
+			config, err := NewConfig(&Config{
+				Input: ConfigInput{
+					Dir: tempDir,
+				},
+				Output: ConfigOutput{
+					FieldOrder: tt.order,
+				},
+				Elements: []ConfigTag{
+					{
+						Name: "json",
+						Input: ConfigTagInput{
+							Mode:        InputModeTypeTag,
+							TagPriority: []string{"json"},
+						},
+					},
+					{
+						Name: "title",
+						Input: ConfigTagInput{
+							Mode:        InputModeTypeTag,
+							TagPriority: []string{"title"},
+						},
+						Output: ConfigTagOutput{
+							Mode: OutputModeStruct,
+						},
+					},
+				},
+			})
+			require.NoError(t, err)
+
+			builder := NewModelBuilder(config)
+			require.NoError(t, builder.scanFile(testFile))
+
+			structModel := builder.model.Packages[tempDir].Structs[0]
+			require.Len(t, structModel.Structs, 1)
+
+			// The constants and the holder struct fields share the same order
+			var constantFields, structFields []string
+			for _, c := range structModel.Constants {
+				constantFields = append(constantFields, strings.TrimPrefix(c.Name, "JsonUser"))
+			}
+			for _, f := range structModel.Structs[0].Fields {
+				structFields = append(structFields, f.Name)
+			}
+			assert.Equal(t, tt.expected, constantFields)
+			assert.Equal(t, tt.expected, structFields)
+		})
+	}
+}
+
+func TestModelBuilderBuildConstantsTagCaseInsensitive(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type User struct {
+	Name string ` + "`JSON:\"name,omitempty\" Title:\"Full Name\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	buildConfig := func(caseInsensitive bool) (*Config, error) {
+		return NewConfig(&Config{
+			Input: ConfigInput{
+				Dir:                tempDir,
+				TagCaseInsensitive: boolPtr(caseInsensitive),
+			},
+			Elements: []ConfigTag{
+				{
+					Name: "json",
+					Input: ConfigTagInput{
+						Mode:        InputModeTypeTag,
+						TagPriority: []string{"json"},
+					},
+				},
+				{
+					Name: "title",
+					Input: ConfigTagInput{
+						Mode:        InputModeTypeTag,
+						TagPriority: []string{"title"},
+					},
+				},
+			},
+		})
+	}
+
+	t.Run("case sensitive by default", func(t *testing.T) {
+		config, err := buildConfig(false)
+		require.NoError(t, err)
+
+		builder := NewModelBuilder(config)
+		require.NoError(t, builder.scanFile(testFile))
+		assert.Len(t, builder.model.Packages, 0)
+	})
+
+	t.Run("case insensitive", func(t *testing.T) {
+		config, err := buildConfig(true)
+		require.NoError(t, err)
+
+		builder := NewModelBuilder(config)
+		require.NoError(t, builder.scanFile(testFile))
+
+		constants := map[string]string{}
+		for _, c := range builder.model.Packages[tempDir].Structs[0].Constants {
+			constants[c.Name] = c.Value
+		}
+		assert.Equal(t, map[string]string{"JsonUserName": "name", "TitleUserName": "Full Name"}, constants)
+	})
+}
+
+func TestModelBuilderBuildConflictingBuildTaggedStructs(t *testing.T) {
+	tempDir := t.TempDir()
+
+	files := map[string]string{
+		"user_linux.go": `//go:build linux
+
+package main
+
+type User struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+`,
+		"user_other.go": `//go:build !linux
+
+package main
+
+type User struct {
+	Name  string ` + "`json:\"name\"`" + `
+	Email string ` + "`json:\"email\"`" + `
+}
+`,
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644))
+	}
+
+	config, err := NewConfig(&Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Elements: []ConfigTag{
+			{Name: "json"},
+		},
+	})
+	require.NoError(t, err)
+
+	_, err = NewModelBuilder(config).Build()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "struct User is declared in")
+	assert.Contains(t, err.Error(), `"linux"`)
+	assert.Contains(t, err.Error(), `"!linux"`)
+
+	// Excluding one of the definitions resolves the conflict
+	config.Input.Exclude = []string{"user_other.go"}
+	model, err := NewModelBuilder(config).Build()
+	require.NoError(t, err)
+	assert.Len(t, model.Packages[tempDir].Structs, 1)
+}
+
+func TestModelBuilderBuildRequireFieldTag(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "models.go")
+	content := `package main
+
+type User struct {
+	Name string ` + "`json:\"name\"`" + `
+	Age  int
+}
+
+type Counter struct {
+	Hits int ` + "`db:\"hits\"`" + `
+	Last int
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config, err := NewConfig(&Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+			Struct: ConfigInputStruct{
+				RequireFieldTag: "json",
+			},
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "field",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeField,
+					TagPriority: []string{"field"},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	builder := NewModelBuilder(config)
+	require.NoError(t, builder.scanFile(testFile))
+
+	structs := builder.model.Packages[tempDir].Structs
+	require.Len(t, structs, 1)
+	assert.Equal(t, "User", structs[0].Name)
+	// All the fields of a matching struct are generated
+	assert.Len(t, structs[0].Constants, 2)
+}
+
+func TestModelBuilderBuildConstantsWithValueMap(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type User struct {
+	Name  string ` + "`json:\"name\"`" + `
+	Email string ` + "`json:\"email\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config, err := NewConfig(&Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "json",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTag,
+					TagPriority: []string{"json"},
+					ValueMap:    map[string]string{"Name": "full_name"},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	builder := NewModelBuilder(config)
+	require.NoError(t, builder.scanFile(testFile))
+
+	constants := map[string]string{}
+	for _, c := range builder.model.Packages[tempDir].Structs[0].Constants {
+		constants[c.Name] = c.Value
+	}
+	assert.Equal(t, map[string]string{"JsonUserName": "full_name", "JsonUserEmail": "email"}, constants)
+}
+
+func TestModelBuilderBuildConstantsIncludePackage(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package model
+
+type User struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	tests := []struct {
+		name          string
+		format        ConstantFormatType
+		prefix        string
+		prefixLiteral bool
+		expected      string
+	}{
+		{name: "pascal", format: ConstantFormatPascal, expected: "ModelJsonUserName"},
+		{name: "camel", format: ConstantFormatCamel, expected: "modelJsonUserName"},
+		{name: "snake upper", format: ConstantFormatSnakeUpper, expected: "MODEL_JSON_USER_NAME"},
+		{name: "pascal literal prefix", format: ConstantFormatPascal, prefix: "JSON", prefixLiteral: true, expected: "ModelJSONUserName"},
+		{name: "snake literal prefix", format: ConstantFormatSnake, prefix: "JSON", prefixLiteral: true, expected: "model_JSON_user_name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := NewConfig(&Config{
+				Input: ConfigInput{
+					Dir: tempDir,
+				},
+				Elements: []ConfigTag{
+					{
+						Name: "json",
+						Input: ConfigTagInput{
+							Mode:        InputModeTypeTag,
+							TagPriority: []string{"json"},
+						},
+						Output: ConfigTagOutput{
+							Format: ConfigTagOutputFormat{
+								Struct:         tt.format,
+								Prefix:         tt.prefix,
+								PrefixLiteral:  boolPtr(tt.prefixLiteral),
+								IncludePackage: boolPtr(true),
+							},
+						},
+					},
+				},
+			})
+			require.NoError(t, err)
+
+			builder := NewModelBuilder(config)
+			require.NoError(t, builder.scanFile(testFile))
+
+			constants := builder.model.Packages[tempDir].Structs[0].Constants
+			require.Len(t, constants, 1)
+			assert.Equal(t, tt.expected, constants[0].Name)
+		})
+	}
+}
+
+func TestModelBuilderBuildElementStructsMatching(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type User struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+
+type UserDTO struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config, err := NewConfig(&Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Elements: []ConfigTag{
+			{
+				Name:            "json",
+				StructsMatching: "DTO$",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTag,
+					TagPriority: []string{"json"},
+				},
+			},
+			{
+				Name: "field",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeField,
+					TagPriority: []string{"field"},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	builder := NewModelBuilder(config)
+	require.NoError(t, builder.scanFile(testFile))
+
+	constants := map[string][]string{}
+	for _, s := range builder.model.Packages[tempDir].Structs {
+		for _, c := range s.Constants {
+			constants[s.Name] = append(constants[s.Name], c.Name)
+		}
+	}
+	assert.Equal(t, map[string][]string{
+		"User":    {"FieldUserName"},
+		"UserDTO": {"JsonUserDtoName", "FieldUserDtoName"},
+	}, constants)
+}
+
+func TestModelBuilderBuildTypeNameConstants(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+import "time"
+
+type User struct {
+	Name     string
+	Tags     []string
+	Scores   map[string]int
+	Birthday *time.Time
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config, err := NewConfig(&Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "type",
+				Input: ConfigTagInput{
+					Mode: InputModeTypeGoType,
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	builder := NewModelBuilder(config)
+	require.NoError(t, builder.scanFile(testFile))
+
+	pkg := builder.model.Packages[tempDir]
+	constants := map[string]string{}
+	for _, c := range pkg.Structs[0].Constants {
+		constants[c.Name] = c.Value
+	}
+	assert.Equal(t, map[string]string{
+		"TypeUserName":     "string",
+		"TypeUserTags":     "[]string",
+		"TypeUserScores":   "map[string]int",
+		"TypeUserBirthday": "*time.Time",
+	}, constants)
+	// Type names are plain strings, so no imports are needed
+	assert.Empty(t, pkg.Imports)
+}
+
+func TestModelBuilderBuildConstantsWithQuotedTagOptions(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type User struct {
+	Role   string ` + "`validate:\"oneof='admin,user'\" json:\"role,omitempty\"`" + `
+	Status string ` + "`validate:\"required,oneof='on,off'\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config, err := NewConfig(&Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "validate",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTag,
+					TagPriority: []string{"validate"},
+				},
+			},
+			{
+				Name: "json",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTag,
+					TagPriority: []string{"json"},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	builder := NewModelBuilder(config)
+	require.NoError(t, builder.scanFile(testFile))
+
+	constants := map[string]string{}
+	for _, c := range builder.model.Packages[tempDir].Structs[0].Constants {
+		constants[c.Name] = c.Value
+	}
+	assert.Equal(t, map[string]string{
+		"ValidateUserRole":   "oneof='admin,user'",
+		"JsonUserRole":       "role",
+		"ValidateUserStatus": "required",
+	}, constants)
+}
+
+func TestModelBuilderBuildStrictUnresolvedType(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type User struct {
+	Name string
+	Ref  missing.Thing
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	buildConfig := func(strict bool) (*Config, error) {
+		return NewConfig(&Config{
+			Input: ConfigInput{
+				Dir: tempDir,
+			},
+			Output: ConfigOutput{
+				Strict: boolPtr(strict),
+			},
+			Getters: []ConfigGetter{
+				{
+					Name:    "Get",
+					Returns: []string{":value"},
+				},
+			},
+		})
+	}
+
+	t.Run("best effort by default", func(t *testing.T) {
+		config, err := buildConfig(false)
+		require.NoError(t, err)
+
+		builder := NewModelBuilder(config)
+		require.NoError(t, builder.scanFile(testFile))
+		assert.Len(t, builder.model.Packages[tempDir].Structs[0].Getters, 2)
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		config, err := buildConfig(true)
+		require.NoError(t, err)
+
+		builder := NewModelBuilder(config)
+		err = builder.scanFile(testFile)
+		assert.EqualError(t, err, testFile+":5: type missing.Thing of field User.Ref can't be resolved to an import path (output.strict)")
+	})
+}
+
+func TestModelBuilderBuildGettersNoneName(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type User struct {
+	Name string ` + "`json:\"name\" title:\"Name\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config, err := NewConfig(&Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "json",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTag,
+					TagPriority: []string{"json"},
+				},
+				Output: ConfigTagOutput{
+					Mode:     OutputModeNone,
+					NoneName: "wireName",
+				},
+			},
+			{
+				Name: "title",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTag,
+					TagPriority: []string{"title"},
+				},
+				Output: ConfigTagOutput{
+					Mode: OutputModeNone,
+				},
+			},
+		},
+		Getters: []ConfigGetter{
+			{
+				Name:    "Get",
+				Returns: []string{"json", "title"},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	builder := NewModelBuilder(config)
+	require.NoError(t, builder.scanFile(testFile))
+
+	getters := builder.model.Packages[tempDir].Structs[0].Getters
+	require.Len(t, getters, 1)
+	assert.Equal(t, &NoneOutput{Name: "wireName", Value: "name"}, getters[0].Returns[0].None)
+	// Without none_name the element name is used
+	assert.Equal(t, &NoneOutput{Name: "title", Value: "Name"}, getters[0].Returns[1].None)
+}
+
+func TestModelBuilderBuildConstantsWithJoinedTags(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type User struct {
+	_     struct{} ` + "`db:\"users\"`" + `
+	Name  string   ` + "`json:\"name,omitempty\"`" + `
+	Email string   ` + "`db:\"accounts\" json:\"email\"`" + `
+	Age   int
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config, err := NewConfig(&Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "column",
+				Input: ConfigTagInput{
+					Join: ConfigTagInputJoin{
+						Tags:      []string{"db", "json"},
+						Separator: ".",
+					},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	builder := NewModelBuilder(config)
+	require.NoError(t, builder.scanFile(testFile))
+
+	constants := map[string]string{}
+	for _, c := range builder.model.Packages[tempDir].Structs[0].Constants {
+		constants[c.Name] = c.Value
+	}
+	// Field tags win over struct-level ones, and fields missing a tag are skipped
+	assert.Equal(t, map[string]string{
+		"ColumnUserName":  "users.name",
+		"ColumnUserEmail": "accounts.email",
+	}, constants)
+
+	t.Run("with unexported fields", func(t *testing.T) {
+		config, err := NewConfig(&Config{
+			Input: ConfigInput{
+				Dir:   tempDir,
+				Field: ConfigInputField{IncludeUnexported: boolPtr(true)},
+			},
+			Elements: []ConfigTag{
+				{Name: "json"},
+				{
+					Name: "column",
+					Input: ConfigTagInput{
+						Join: ConfigTagInputJoin{
+							Tags:      []string{"db", "json"},
+							Separator: ".",
+						},
+					},
+				},
+			},
+		})
+		require.NoError(t, err)
+
+		builder := NewModelBuilder(config)
+		require.NoError(t, builder.scanFile(testFile))
+
+		// The blank field carrying the struct-level tag isn't a field without
+		// a usable value
+		assert.Empty(t, builder.model.Errors)
+		constants := map[string]string{}
+		for _, c := range builder.model.Packages[tempDir].Structs[0].Constants {
+			constants[c.Name] = c.Value
+		}
+		assert.Equal(t, map[string]string{
+			"JsonUserName":    "name",
+			"JsonUserEmail":   "email",
+			"JsonUserAge":     "Age",
+			"ColumnUserName":  "users.name",
+			"ColumnUserEmail": "accounts.email",
+		}, constants)
+	})
+}
+
+func TestModelBuilderBuildMissingPackageClause(t *testing.T) {
+	tempDir := t.TempDir()
+
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "user.go"), []byte(`package main
+
+type User struct {
+	Name string `+"`json:\"name\"`"+`
+}
+`), 0644))
+
+	// A file matched by input.include that isn't part of any package
+	brokenFile := filepath.Join(tempDir, "broken.go")
+	require.NoError(t, os.WriteFile(brokenFile, []byte(`type Orphan struct {
+	Name string
+}
+`), 0644))
+
+	config, err := NewConfig(&Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Elements: []ConfigTag{
+			{Name: "json"},
+		},
+	})
+	require.NoError(t, err)
+
+	var model *Model
+	require.NotPanics(t, func() {
+		model, err = NewModelBuilder(config).Build()
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, model.FilesScanned)
+	require.Len(t, model.Errors, 1)
+	assert.Equal(t, brokenFile, model.Errors[0].File)
+	assert.Equal(t, 1, model.Errors[0].Line)
+	// The parser reports the missing clause, so the file is never scanned
+	assert.Contains(t, model.Errors[0].Message, "failed to parse file")
+	assert.Contains(t, model.Errors[0].Message, "expected 'package'")
+	require.Len(t, model.Packages[tempDir].Structs, 1)
+	assert.Equal(t, "User", model.Packages[tempDir].Structs[0].Name)
+}
+
+func TestModelBuilderBuildBrokenPackageClause(t *testing.T) {
+	tempDir := t.TempDir()
+
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "user.go"), []byte(`package main
+
+type User struct {
+	Name string `+"`json:\"name\"`"+`
+}
+`), 0644))
+
+	// Package clauses without a valid name, both scanned through package:NAME
+	// includes and plain globs
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "empty.go"), []byte("package\n\ntype Empty struct{}\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "number.go"), []byte("package 123\n\ntype Number struct{}\n"), 0644))
+
+	for _, include := range []string{"package:main", "**/*.go"} {
+		t.Run(include, func(t *testing.T) {
+			config, err := NewConfig(&Config{
+				Input: ConfigInput{
+					Dir:     tempDir,
+					Include: []string{include},
+				},
+				Elements: []ConfigTag{
+					{Name: "json"},
+				},
+			})
+			require.NoError(t, err)
+
+			var model *Model
+			require.NotPanics(t, func() {
+				model, err = NewModelBuilder(config).Build()
+			})
+			require.NoError(t, err)
+
+			require.Len(t, model.Packages[tempDir].Structs, 1)
+			assert.Equal(t, "User", model.Packages[tempDir].Structs[0].Name)
+			if include == "**/*.go" {
+				assert.Len(t, model.Errors, 2)
+			}
+		})
+	}
+}
+
+func TestModelBuilderBuildGetterStructsMatching(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type UserEntity struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+
+type UserDTO struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config, err := NewConfig(&Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "json",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTag,
+					TagPriority: []string{"json"},
+				},
+			},
+		},
+		Getters: []ConfigGetter{
+			{
+				Name:            "Val",
+				Returns:         []string{":value"},
+				StructsMatching: "Entity$",
+			},
+			{
+				Name:    "Json",
+				Returns: []string{"json"},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	builder := NewModelBuilder(config)
+	require.NoError(t, builder.scanFile(testFile))
+
+	getters := map[string][]string{}
+	for _, s := range builder.model.Packages[tempDir].Structs {
+		for _, g := range s.Getters {
+			getters[s.Name] = append(getters[s.Name], g.Name)
+		}
+	}
+	assert.Equal(t, map[string][]string{
+		"UserEntity": {"ValName", "JsonName"},
+		"UserDTO":    {"JsonName"},
+	}, getters)
+}
+
+func TestModelBuilderBuildConstantsKeyValueTagSyntax(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type User struct {
+	ID    int    ` + "`gorm:\"primaryKey;column:id\"`" + `
+	Name  string ` + "`gorm:\"COLUMN:user_name;size:64\"`" + `
+	Email string ` + "`gorm:\"size:128\" json:\"email\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config, err := NewConfig(&Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "column",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTag,
+					TagPriority: []string{"gorm"},
+					TagSyntax:   TagSyntaxKeyValue,
+					OptionKey:   "column",
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	builder := NewModelBuilder(config)
+	require.NoError(t, builder.scanFile(testFile))
+
+	constants := map[string]string{}
+	for _, c := range builder.model.Packages[tempDir].Structs[0].Constants {
+		constants[c.Name] = c.Value
+	}
+	assert.Equal(t, map[string]string{"ColumnUserId": "id", "ColumnUserName": "user_name"}, constants)
+}
+
+func TestModelBuilderBuildConstantsProtobufTagSyntax(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.pb.go")
+	content := `package main
+
+type User struct {
+	UserName string ` + "`protobuf:\"bytes,1,opt,name=user_name,json=userName,proto3\" json:\"user_name,omitempty\"`" + `
+	Age      int32  ` + "`protobuf:\"varint,2,opt,name=age,proto3\" json:\"age,omitempty\"`" + `
+	Kind     isUser_Kind ` + "`protobuf_oneof:\"kind\"`" + `
+	Legacy   string ` + "`protobuf:\"bytes,3,opt\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config, err := NewConfig(&Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "proto",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTag,
+					TagPriority: []string{"protobuf"},
+					TagSyntax:   TagSyntaxProtobuf,
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	builder := NewModelBuilder(config)
+	require.NoError(t, builder.scanFile(testFile))
+
+	// Descriptors without the name option give no value
+	constants := map[string]string{}
+	for _, c := range builder.model.Packages[tempDir].Structs[0].Constants {
+		constants[c.Name] = c.Value
+	}
+	assert.Equal(t, map[string]string{"ProtoUserUserName": "user_name", "ProtoUserAge": "age"}, constants)
+}
+
+func TestModelBuilderBuildConstantsSkipFields(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type User struct {
+	ID          int    ` + "`json:\"id\"`" + `
+	internal    string
+	Name, Email string
+	Version     int ` + "`json:\"version\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	buildConstants := func(field ConfigInputField) []string {
+		config, err := NewConfig(&Config{
+			Input: ConfigInput{
+				Dir:   tempDir,
+				Field: field,
+			},
+			Elements: []ConfigTag{
+				{
+					Name: "field",
+					Input: ConfigTagInput{
+						Mode: InputModeTypeField,
+					},
+				},
+			},
+		})
+		require.NoError(t, err)
+
+		builder := NewModelBuilder(config)
+		require.NoError(t, builder.scanFile(testFile))
+
+		var names []string
+		for _, c := range builder.model.Packages[tempDir].Structs[0].Constants {
+			names = append(names, c.Name)
+		}
+		return names
+	}
+
+	// The unexported field isn't includable, so it isn't counted
+	assert.Equal(t, []string{"FieldUserName", "FieldUserEmail", "FieldUserVersion"}, buildConstants(ConfigInputField{SkipFirst: 1}))
+	assert.Equal(t, []string{"FieldUserEmail"}, buildConstants(ConfigInputField{SkipFirst: 2, SkipLast: 1}))
+}
+
+func TestModelBuilderBuildFastSkip(t *testing.T) {
+	tempDir := t.TempDir()
+
+	files := map[string]string{
+		"user.go": "package main\n\ntype User struct {\n\tName string `json:\"name\"`\n}\n",
+		// Structs written without the usual spacing are still parsed
+		"event.go":   "package main\n\ntype Event struct{ Kind string }\n\ntype Point = struct\n{\n\tX int\n}\n",
+		"helpers.go": "package main\n\nfunc helper() string {\n\treturn \"\"\n}\n",
+		"consts.go":  "package main\n\nconst Version = \"1.0\"\n",
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644))
+	}
+
+	build := func(fastSkip bool) *Model {
+		config, err := NewConfig(&Config{
+			Input: ConfigInput{
+				Dir:      tempDir,
+				FastSkip: boolPtr(fastSkip),
+				Struct: ConfigInputStruct{
+					IncludeAnonymous: boolPtr(true),
+				},
+			},
+			Elements: []ConfigTag{
+				{Name: "json"},
+			},
+		})
+		require.NoError(t, err)
+
+		model, err := NewModelBuilder(config).Build()
+		require.NoError(t, err)
+		return model
+	}
+
+	full := build(false)
+	fast := build(true)
+
+	assert.Equal(t, 4, full.FilesScanned)
+	assert.Equal(t, 0, full.FilesSkipped)
+	assert.Equal(t, 4, fast.FilesScanned)
+	assert.Equal(t, 2, fast.FilesSkipped)
+
+	structNames := func(model *Model) []string {
+		var names []string
+		for _, s := range model.Packages[tempDir].Structs {
+			names = append(names, s.Name)
+		}
+		return names
+	}
+	assert.ElementsMatch(t, []string{"Event", "Point", "User"}, structNames(full))
+	assert.ElementsMatch(t, structNames(full), structNames(fast))
+}
+
+func TestModelBuilderBuildJSONTypeConstants(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+import "time"
+
+type Status string
+
+type User struct {
+	Name      string
+	Age       int
+	Score     *float64
+	Active    bool
+	Tags      []string
+	Avatar    []byte
+	Meta      map[string]string
+	Status    Status
+	CreatedAt time.Time
+	Extra     any
+	Role      Role
+	Address   Address
+	Timeout   time.Duration
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+	// Named types declared in other files of the package are followed too
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "role.go"), []byte(`package main
+
+type Role int
+
+type Address struct {
+	City string
+}
+`), 0644))
+
+	config, err := NewConfig(&Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Output: ConfigOutput{
+			EmitJSONType: boolPtr(true),
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "json",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTag,
+					TagPriority: []string{"json"},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	builder := NewModelBuilder(config)
+	require.NoError(t, builder.scanFile(testFile))
+
+	var user *StructModel
+	for _, s := range builder.model.Packages[tempDir].Structs {
+		if s.Name == "User" {
+			user = s
+		}
+	}
+	require.NotNil(t, user)
+
+	constants := map[string]string{}
+	for _, c := range user.Constants {
+		constants[c.Name] = c.Value
+	}
+	assert.Equal(t, map[string]string{
+		"JsonTypeUserName":      "string",
+		"JsonTypeUserAge":       "number",
+		"JsonTypeUserScore":     "number",
+		"JsonTypeUserActive":    "boolean",
+		"JsonTypeUserTags":      "array",
+		"JsonTypeUserAvatar":    "string",
+		"JsonTypeUserMeta":      "object",
+		"JsonTypeUserStatus":    "string",
+		"JsonTypeUserCreatedAt": "string",
+		"JsonTypeUserRole":      "number",
+		"JsonTypeUserAddress":   "object",
+	}, constants, "time.Duration can't be resolved without type checking, so it gets no constant")
+}
+
+func TestModelBuilderBuildFieldDirectives(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type User struct {
+	Name string ` + "`json:\"name\"`" + `
+	// Password is never serialized
+	//constago:exclude
+	Password string ` + "`json:\"password\"`" + `
+	Token string ` + "`json:\"token\"`" + ` // constago:exclude
+	// The tag wins over the directive
+	//constago:exclude
+	Email string ` + "`json:\"email\" constago:\"include\"`" + `
+	//constago:include
+	secret string ` + "`json:\"secret\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	tests := []struct {
+		name     string
+		explicit bool
+		expected map[string]string
+	}{
+		{
+			name: "directives",
+			expected: map[string]string{
+				"JsonUserName":   "name",
+				"JsonUserEmail":  "email",
+				"JsonUserSecret": "secret",
+			},
+		},
+		{
+			name:     "directives with explicit fields",
+			explicit: true,
+			expected: map[string]string{
+				"JsonUserEmail":  "email",
+				"JsonUserSecret": "secret",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := NewConfig(&Config{
+				Input: ConfigInput{
+					Dir:   tempDir,
+					Field: ConfigInputField{Explicit: boolPtr(tt.explicit)},
+				},
+				Elements: []ConfigTag{
+					{Name: "json"},
+				},
+			})
+			require.NoError(t, err)
+
+			builder := NewModelBuilder(config)
+			require.NoError(t, builder.scanFile(testFile))
+
+			require.Len(t, builder.model.Packages[tempDir].Structs, 1)
+			constants := map[string]string{}
+			for _, c := range builder.model.Packages[tempDir].Structs[0].Constants {
+				constants[c.Name] = c.Value
+			}
+			assert.Equal(t, tt.expected, constants)
+		})
+	}
+}
+
+func TestModelBuilderBuildBlankFieldValue(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type User struct {
+	Name string
+	_    int
+	_    string ` + "`json:\"padding\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config, err := NewConfig(&Config{
+		Input: ConfigInput{
+			Dir:   tempDir,
+			Field: ConfigInputField{IncludeUnexported: boolPtr(true)},
+		},
+		Elements: []ConfigTag{
+			{Name: "json"},
+		},
+	})
+	require.NoError(t, err)
+
+	builder := NewModelBuilder(config)
+	require.NoError(t, builder.scanFile(testFile))
+
+	// The untagged blank field is skipped with an error, the tagged one keeps
+	// its tag value
+	require.Len(t, builder.model.Errors, 1)
+	assert.Equal(t, &ScanError{File: testFile, Line: 5, Message: "field _ of struct User has no usable value for element json"}, builder.model.Errors[0])
+
+	require.Len(t, builder.model.Packages[tempDir].Structs, 1)
+	values := []string{}
+	for _, c := range builder.model.Packages[tempDir].Structs[0].Constants {
+		values = append(values, c.Value)
+	}
+	assert.Equal(t, []string{"Name", "padding"}, values)
+}
+
+func TestModelBuilderBuildStructNameConstant(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type User struct {
+	Name       string
+	StructName string
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	t.Run("identifier case", func(t *testing.T) {
+		config, err := NewConfig(&Config{
+			Input: ConfigInput{
+				Dir: tempDir,
+			},
+			Output: ConfigOutput{
+				EmitStructConstant: boolPtr(true),
+				IdentifierCase:     ConstantFormatSnakeUpper,
+			},
+			Elements: []ConfigTag{
+				{Name: "json"},
+			},
+		})
+		require.NoError(t, err)
+
+		model, err := NewModelBuilder(config).Build()
+		require.NoError(t, err)
+
+		// The struct name constant follows the identifier case like the others
+		constants := model.Packages[tempDir].Structs[0].Constants
+		require.Len(t, constants, 3)
+		assert.Equal(t, &ConstantOutput{Name: "USER_STRUCT_NAME", Value: "User"}, constants[0])
+		assert.Equal(t, "JSON_USER_STRUCT_NAME", constants[2].Name)
+	})
+
+	t.Run("collision", func(t *testing.T) {
+		config, err := NewConfig(&Config{
+			Input: ConfigInput{
+				Dir: tempDir,
+			},
+			Output: ConfigOutput{
+				EmitStructConstant: boolPtr(true),
+			},
+			Elements: []ConfigTag{
+				{
+					Name: "user",
+					Output: ConfigTagOutput{
+						Format: ConfigTagOutputFormat{OmitStruct: boolPtr(true)},
+					},
+				},
+			},
+		})
+		require.NoError(t, err)
+
+		builder := NewModelBuilder(config)
+		err = builder.scanFile(testFile)
+		assert.EqualError(t, err, testFile+":3: constant UserStructName naming struct User collides with a field constant (output.emit_struct_constant)")
+	})
+}
+
+func TestModelBuilderBuildEmptyStructs(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+//constago:include
+type User struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+
+//constago:include
+type Secret struct {
+	Token string ` + "`json:\"token\" constago:\"exclude\"`" + `
+	Key   string ` + "`json:\"key\" constago:\"exclude\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	buildConfig := func(input ConfigInput) (*Config, error) {
+		input.Dir = tempDir
+		input.Struct = ConfigInputStruct{Explicit: boolPtr(true)}
+		return NewConfig(&Config{
+			Input: input,
+			Elements: []ConfigTag{
+				{Name: "json"},
+			},
+		})
+	}
+
+	t.Run("silent by default", func(t *testing.T) {
+		config, err := buildConfig(ConfigInput{})
+		require.NoError(t, err)
+
+		builder := NewModelBuilder(config)
+		require.NoError(t, builder.scanFile(testFile))
+		assert.Empty(t, builder.model.Errors)
+	})
+
+	t.Run("warn", func(t *testing.T) {
+		config, err := buildConfig(ConfigInput{WarnEmptyStructs: boolPtr(true)})
+		require.NoError(t, err)
+
+		builder := NewModelBuilder(config)
+		require.NoError(t, builder.scanFile(testFile))
+		require.Len(t, builder.model.Errors, 1)
+		assert.Equal(t, &ScanError{File: testFile, Line: 9, Message: "struct Secret is included but yields no output"}, builder.model.Errors[0])
+		assert.Len(t, builder.model.Packages[tempDir].Structs, 1)
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		config, err := buildConfig(ConfigInput{FailEmptyStructs: boolPtr(true)})
+		require.NoError(t, err)
+
+		builder := NewModelBuilder(config)
+		err = builder.scanFile(testFile)
+		assert.EqualError(t, err, testFile+":9: struct Secret is included but yields no output (input.fail_empty_structs)")
+	})
+}
+
+func TestModelBuilderBuildConstantsDottedTagValues(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type User struct {
+	Name  string ` + "`json:\"user.name\"`" + `
+	Email string ` + "`json:\"user.contact.email,omitempty\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config, err := NewConfig(&Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "json",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTag,
+					TagPriority: []string{"json"},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	builder := NewModelBuilder(config)
+	require.NoError(t, builder.scanFile(testFile))
+
+	// Only the options after the comma are dropped, the dots are kept
+	require.Len(t, builder.model.Packages[tempDir].Structs, 1)
+	assert.Equal(t, []*ConstantOutput{
+		{Name: "JsonUserName", Value: "user.name"},
+		{Name: "JsonUserEmail", Value: "user.contact.email"},
+	}, builder.model.Packages[tempDir].Structs[0].Constants)
+}
+
+func TestModelBuilderBuildConstantsORMColumnTags(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type GormUser struct {
+	ID    int    ` + "`gorm:\"primaryKey\" db:\"user_id\"`" + `
+	Name  string ` + "`gorm:\"column:user_name;size:64\"`" + `
+	Email string ` + "`gorm:\"size:128\" db:\"email_address\"`" + `
+	Note  string ` + "`gorm:\"not null\"`" + `
+}
+
+type SqlxUser struct {
+	Name  string ` + "`db:\"user_name\"`" + `
+	Email string ` + "`db:\"email_address,omitempty\"`" + `
+	At    string ` + "`db:\"created:at\"`" + `
+	Zone  string ` + "`sql:\"type:text;column:zone_name\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config, err := NewConfig(&Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "column",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTag,
+					TagPriority: []string{"gorm", "db", "sql"},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	builder := NewModelBuilder(config)
+	require.NoError(t, builder.scanFile(testFile))
+
+	constants := map[string]string{}
+	for _, s := range builder.model.Packages[tempDir].Structs {
+		for _, c := range s.Constants {
+			constants[c.Name] = c.Value
+		}
+	}
+	// Gorm settings don't name columns, so the next tag or none is used, while
+	// db values without a column option are taken whole
+	assert.Equal(t, map[string]string{
+		"ColumnGormUserId":    "user_id",
+		"ColumnGormUserName":  "user_name",
+		"ColumnGormUserEmail": "email_address",
+		"ColumnSqlxUserName":  "user_name",
+		"ColumnSqlxUserEmail": "email_address",
+		"ColumnSqlxUserAt":    "created:at",
+		"ColumnSqlxUserZone":  "zone_name",
+	}, constants)
+}
+
+func TestModelBuilderBuildConstantsTypeSuffix(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+import "time"
+
+type User struct {
+	Age       int        ` + "`json:\"age\"`" + `
+	CreatedAt *time.Time ` + "`json:\"created_at\"`" + `
+	Tags      []string   ` + "`json:\"tags\"`" + `
+	Meta      map[string]string ` + "`json:\"meta\"`" + `
+	Address   struct{ City string } ` + "`json:\"address\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config, err := NewConfig(&Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "json",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTag,
+					TagPriority: []string{"json"},
+				},
+				Output: ConfigTagOutput{
+					TypeSuffix: boolPtr(true),
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	builder := NewModelBuilder(config)
+	require.NoError(t, builder.scanFile(testFile))
+
+	var names []string
+	for _, c := range builder.model.Packages[tempDir].Structs[0].Constants {
+		names = append(names, c.Name)
+	}
+	assert.Equal(t, []string{
+		"JsonUserAgeInt",
+		"JsonUserCreatedAtTime",
+		"JsonUserTagsStringSlice",
+		"JsonUserMetaMap",
+		"JsonUserAddress",
+	}, names)
+}
+
+func TestModelBuilderBuildSeveralModulesUnderOneRoot(t *testing.T) {
+	rootDir := t.TempDir()
+	appDir := filepath.Join(rootDir, "app")
+	kitDir := filepath.Join(rootDir, "appkit")
+
+	files := map[string]string{
+		filepath.Join(appDir, "go.mod"): "module \"example.com/app\" // the service\n\ngo 1.22\n",
+		filepath.Join(appDir, "user.go"): `package app
+
+import "example.com/appkit/ids"
+
+type User struct {
+	ID ids.ID
+}
+`,
+		filepath.Join(kitDir, "go.mod"): "module example.com/appkit\n\ngo 1.22\n",
+		filepath.Join(kitDir, "ids", "ids.go"): `package identifiers
+
+type ID string
+`,
+		filepath.Join(kitDir, "account.go"): `package appkit
+
+import "example.com/appkit/ids"
+
+type Account struct {
+	Owner ids.ID
+}
+`,
+	}
+	for path, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	resolvedIn := map[string]string{}
+	original := packageNameResolver
+	packageNameResolver = func(importPath string, moduleDir string) (string, error) {
+		resolvedIn[importPath] = moduleDir
+		return "identifiers", nil
+	}
+	t.Cleanup(func() { packageNameResolver = original })
+
+	config, err := NewConfig(&Config{
+		Input: ConfigInput{
+			Dir: rootDir,
+		},
+		Getters: []ConfigGetter{
+			{
+				Name:    "Get",
+				Returns: []string{":value"},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	model, err := NewModelBuilder(config).Build()
+	require.NoError(t, err)
+
+	// The app module imports a package of its sibling module, which is
+	// resolved from the app module rather than read as one of its own
+	require.Contains(t, model.Packages, appDir)
+	require.Contains(t, model.Packages[appDir].Imports, "example.com/appkit/ids")
+	assert.Equal(t, "identifiers", model.Packages[appDir].Imports["example.com/appkit/ids"].Name)
+	assert.Equal(t, map[string]string{"example.com/appkit/ids": appDir}, resolvedIn)
+
+	// The appkit module reads its own package from disk
+	require.Contains(t, model.Packages, kitDir)
+	require.Contains(t, model.Packages[kitDir].Imports, "example.com/appkit/ids")
+	assert.Equal(t, "identifiers", model.Packages[kitDir].Imports["example.com/appkit/ids"].Name)
+}
+
+func TestIsModulePackage(t *testing.T) {
+	assert.True(t, isModulePackage("example.com/app", "example.com/app"))
+	assert.True(t, isModulePackage("example.com/app/model", "example.com/app"))
+	assert.False(t, isModulePackage("example.com/appkit/ids", "example.com/app"))
+	assert.False(t, isModulePackage("example.com/app", ""))
+}
+
+func TestModelBuilderBuildUnexportedStructAndFieldMatrix(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type User struct {
+	Name, age string
+	Email     string
+	secret    string ` + "`constago:\"include\"`" + `
+}
+
+//constago:include
+type config struct {
+	Host, port string
+	token      string ` + "`constago:\"include\"`" + `
+}
+
+type account struct {
+	ID    string
+	owner string
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	tests := []struct {
+		name                    string
+		structIncludeUnexported bool
+		fieldIncludeUnexported  bool
+		expectedValues          map[string][]string
+	}{
+		{
+			name: "unexported structs and fields excluded",
+			expectedValues: map[string][]string{
+				"User":   {"Name", "Email", "secret"},
+				"config": {"Host", "token"},
+			},
+		},
+		{
+			name:                   "unexported fields included",
+			fieldIncludeUnexported: true,
+			expectedValues: map[string][]string{
+				"User":   {"Name", "age", "Email", "secret"},
+				"config": {"Host", "port", "token"},
+			},
+		},
+		{
+			name:                    "unexported structs included",
+			structIncludeUnexported: true,
+			expectedValues: map[string][]string{
+				"User":    {"Name", "Email", "secret"},
+				"config":  {"Host", "token"},
+				"account": {"ID"},
+			},
+		},
+		{
+			name:                    "unexported structs and fields included",
+			structIncludeUnexported: true,
+			fieldIncludeUnexported:  true,
+			expectedValues: map[string][]string{
+				"User":    {"Name", "age", "Email", "secret"},
+				"config":  {"Host", "port", "token"},
+				"account": {"ID", "owner"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := NewConfig(&Config{
+				Input: ConfigInput{
+					Dir: tempDir,
+					Struct: ConfigInputStruct{
+						IncludeUnexported: boolPtr(tt.structIncludeUnexported),
+					},
+					Field: ConfigInputField{
+						IncludeUnexported: boolPtr(tt.fieldIncludeUnexported),
+					},
+				},
+				Elements: []ConfigTag{
+					{
+						Name: "field",
+						Input: ConfigTagInput{
+							Mode: InputModeTypeField,
+						},
+					},
+				},
+			})
+			require.NoError(t, err)
+
+			builder := NewModelBuilder(config)
+			require.NoError(t, builder.scanFile(testFile))
+
+			values := map[string][]string{}
+			for _, structModel := range builder.model.Packages[tempDir].Structs {
+				for _, constant := range structModel.Constants {
+					values[structModel.Name] = append(values[structModel.Name], constant.Value)
+				}
+			}
+			assert.Equal(t, tt.expectedValues, values)
+		})
+	}
+}
+
+func TestModelBuilderBuildRequireModule(t *testing.T) {
+	rootDir := t.TempDir()
+	moduleDir := filepath.Join(rootDir, "app")
+	looseDir := filepath.Join(rootDir, "scripts")
+
+	files := map[string]string{
+		filepath.Join(moduleDir, "go.mod"):  "module example.com/app\n\ngo 1.22\n",
+		filepath.Join(moduleDir, "user.go"): "package app\n\ntype User struct{ Name string }\n",
+		filepath.Join(looseDir, "tool.go"):  "package main\n\ntype Tool struct{ Name string }\n",
+	}
+	for path, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	build := func(requireModule bool) *Model {
+		config, err := NewConfig(&Config{
+			Input: ConfigInput{
+				Dir:           rootDir,
+				RequireModule: boolPtr(requireModule),
+			},
+			Elements: []ConfigTag{
+				{Name: "json"},
+			},
+		})
+		require.NoError(t, err)
+
+		model, err := NewModelBuilder(config).Build()
+		require.NoError(t, err)
+		return model
+	}
+
+	model := build(false)
+	assert.Contains(t, model.Packages, moduleDir)
+	assert.Contains(t, model.Packages, looseDir)
+	assert.Equal(t, 0, model.FilesSkipped)
+
+	// The file outside any module is skipped
+	model = build(true)
+	assert.Contains(t, model.Packages, moduleDir)
+	assert.NotContains(t, model.Packages, looseDir)
+	assert.Equal(t, 2, model.FilesScanned)
+	assert.Equal(t, 1, model.FilesSkipped)
+}
+
+func TestTransformFieldValueMultiRuneSeparator(t *testing.T) {
+	tests := []struct {
+		value    string
+		caseType TransformCaseType
+		sep      string
+		expected string
+	}{
+		{"FirstName", TransformCaseLower, "__", "first__name"},
+		{"FirstName", TransformCaseUpper, "__", "FIRST__NAME"},
+		{"first_name", TransformCaseCamel, "__", "first__Name"},
+		{"first_name", TransformCaseAsIs, "__", "first__name"},
+		{"FirstName", TransformCaseLower, "::", "first::name"},
+		{"user-home_url", TransformCasePascal, "::", "User::Home::Url"},
+		{"first__name", TransformCaseLower, "__", "first__name"},
+		{"name", TransformCaseLower, "::", "name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.caseType)+" "+tt.sep+" "+tt.value, func(t *testing.T) {
+			assert.Equal(t, tt.expected, transformFieldValue(tt.value, tt.caseType, tt.sep))
+		})
+	}
+}
+
+func TestTransformFieldValueKebab(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"FirstName", "first-name"},
+		{"firstName", "first-name"},
+		{"first_name", "first-name"},
+		{"UserID", "user-id"},
+		{"Address2Line", "address2-line"},
+		{"name", "name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			assert.Equal(t, tt.expected, transformFieldValue(tt.value, TransformCaseLower, "-"))
+		})
+	}
+}
+
+func TestModelBuilderBuildConstantsExpandOneof(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type User struct {
+	Status string ` + "`validate:\"required,oneof=active banned\"`" + `
+	Stage  string ` + "`validate:\"oneof='in progress' done\"`" + `
+	Size   string ` + "`validate:\"oneof='small,medium' large,required\"`" + `
+	Name   string ` + "`validate:\"required\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config, err := NewConfig(&Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "validate",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTag,
+					TagPriority: []string{"validate"},
+					ExpandOneof: boolPtr(true),
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	builder := NewModelBuilder(config)
+	require.NoError(t, builder.scanFile(testFile))
+
+	constants := map[string]string{}
+	for _, c := range builder.model.Packages[tempDir].Structs[0].Constants {
+		constants[c.Name] = c.Value
+	}
+	assert.Equal(t, map[string]string{
+		"ValidateUserStatusActive":    "active",
+		"ValidateUserStatusBanned":    "banned",
+		"ValidateUserStageInProgress": "in progress",
+		"ValidateUserStageDone":       "done",
+		"ValidateUserSizeLarge":       "large",
+	}, constants)
+	// Quoted values keep their commas, so they're reported whole
+	if assert.Len(t, builder.model.Errors, 1) {
+		assert.Contains(t, builder.model.Errors[0].Message, `oneof value "small,medium" of field Size`)
+	}
+}
+
+func TestModelBuilderBuildIncludeReferenced(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "order.go")
+	content := `package main
+
+import "time"
+
+//constago:include
+type Order struct {
+	ID       string            ` + "`json:\"id\"`" + `
+	Items    []Item            ` + "`json:\"items\"`" + `
+	Notes    map[string]*note  ` + "`json:\"notes\"`" + `
+	Internal *Internal         ` + "`json:\"internal\"`" + `
+	Created  time.Time         ` + "`json:\"created\"`" + `
+}
+
+type Item struct {
+	SKU    string  ` + "`json:\"sku\"`" + `
+	Parent *Order  ` + "`json:\"parent\"`" + `
+}
+
+type note struct {
+	Text string ` + "`json:\"text\"`" + `
+}
+
+//constago:exclude
+type Internal struct {
+	Secret string ` + "`json:\"secret\"`" + `
+}
+
+type Unrelated struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	build := func(includeReferenced bool) []string {
+		config, err := NewConfig(&Config{
+			Input: ConfigInput{
+				Dir:               tempDir,
+				IncludeReferenced: boolPtr(includeReferenced),
+				Struct: ConfigInputStruct{
+					Explicit: boolPtr(true),
+				},
+			},
+			Elements: []ConfigTag{
+				{
+					Name: "json",
+					Input: ConfigTagInput{
+						Mode:        InputModeTypeTag,
+						TagPriority: []string{"json"},
+					},
+				},
+			},
+		})
+		require.NoError(t, err)
+
+		builder := NewModelBuilder(config)
+		require.NoError(t, builder.scanFile(testFile))
+
+		var names []string
+		for _, structModel := range builder.model.Packages[tempDir].Structs {
+			names = append(names, structModel.Name)
+		}
+		return names
+	}
+
+	// Item and note are pulled in by Order, whatever the struct rules, while
+	// the cycle back to Order is followed once and excluded structs stay out
+	assert.Equal(t, []string{"Order", "Item", "note"}, build(true))
+	assert.Equal(t, []string{"Order"}, build(false))
+}
+
+func TestModelBuilderBuildIncludeReferencedAcrossFiles(t *testing.T) {
+	tempDir := t.TempDir()
+
+	files := map[string]string{
+		"order.go": `package main
+
+//constago:include
+type Order struct {
+	ID    string ` + "`json:\"id\"`" + `
+	Items []Item ` + "`json:\"items\"`" + `
+}
+`,
+		"item.go": `package main
+
+type Item struct {
+	SKU  string ` + "`json:\"sku\"`" + `
+	Tags []*Tag  ` + "`json:\"tags\"`" + `
+}
+
+type Unrelated struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+`,
+		"tag.go": `package main
+
+type Tag struct {
+	Label string ` + "`json:\"label\"`" + `
+}
+`,
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644))
+	}
+
+	config, err := NewConfig(&Config{
+		Input: ConfigInput{
+			Dir:               tempDir,
+			IncludeReferenced: boolPtr(true),
+			Struct: ConfigInputStruct{
+				Explicit: boolPtr(true),
+			},
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "json",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTag,
+					TagPriority: []string{"json"},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	model, err := NewModelBuilder(config).Build()
+	require.NoError(t, err)
+
+	// Item is pulled in from item.go by Order, and Tag from tag.go by Item
+	var names []string
+	for _, structModel := range model.Packages[tempDir].Structs {
+		names = append(names, structModel.Name)
+	}
+	assert.ElementsMatch(t, []string{"Order", "Item", "Tag"}, names)
+}
+
+func TestModelBuilderBuildStructModeDirective(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type User struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+
+//constago:mode=struct
+type Company struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+
+//constago:mode=table
+type Order struct {
+	ID string ` + "`json:\"id\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config, err := NewConfig(&Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "json",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTagThenField,
+					TagPriority: []string{"json"},
+				},
+				Output: ConfigTagOutput{
+					Mode: OutputModeConstant,
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	builder := NewModelBuilder(config)
+	require.NoError(t, builder.scanFile(testFile))
+
+	structs := builder.model.Packages[tempDir].Structs
+	require.Len(t, structs, 3)
+
+	// The element mode applies to structs without the directive
+	assert.Equal(t, "User", structs[0].Name)
+	require.Len(t, structs[0].Constants, 1)
+	assert.Equal(t, "JsonUserName", structs[0].Constants[0].Name)
+	assert.Empty(t, structs[0].Structs)
+
+	assert.Equal(t, "Company", structs[1].Name)
+	assert.Empty(t, structs[1].Constants)
+	require.Len(t, structs[1].Structs, 1)
+	assert.Equal(t, "JsonCompany", structs[1].Structs[0].Name)
+	require.Len(t, structs[1].Structs[0].Fields, 1)
+	assert.Equal(t, "name", structs[1].Structs[0].Fields[0].Value)
+
+	// Invalid modes are reported and ignored
+	assert.Equal(t, "Order", structs[2].Name)
+	require.Len(t, structs[2].Constants, 1)
+	require.Len(t, builder.model.Errors, 1)
+	assert.Contains(t, builder.model.Errors[0].Message, `invalid //constago:mode directive "table", must be none, struct, constant, doc`)
+	assert.Equal(t, 12, builder.model.Errors[0].Line)
+}
+
+func TestModelBuilderBuildConstantsOmitStruct(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type User struct {
+	Name  string ` + "`json:\"name\"`" + `
+	Email string ` + "`json:\"email\"`" + `
+}
+
+type Company struct {
+	Name  string ` + "`json:\"name\"`" + `
+	Phone string ` + "`json:\"phone\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	build := func() (*Model, error) {
+		config, err := NewConfig(&Config{
+			Input: ConfigInput{
+				Dir: tempDir,
+			},
+			Elements: []ConfigTag{
+				{
+					Name: "json",
+					Input: ConfigTagInput{
+						Mode:        InputModeTypeTag,
+						TagPriority: []string{"json"},
+					},
+					Output: ConfigTagOutput{
+						Format: ConfigTagOutputFormat{
+							OmitStruct: boolPtr(true),
+						},
+					},
+				},
+			},
+		})
+		require.NoError(t, err)
+		return NewModelBuilder(config).Build()
+	}
+
+	model, err := build()
+	require.NoError(t, err)
+
+	names := map[string][]string{}
+	for _, structModel := range model.Packages[tempDir].Structs {
+		for _, c := range structModel.Constants {
+			names[structModel.Name] = append(names[structModel.Name], c.Name)
+		}
+	}
+	// The shared JsonName constant is declared once
+	assert.Equal(t, map[string][]string{
+		"User":    {"JsonName", "JsonEmail"},
+		"Company": {"JsonPhone"},
+	}, names)
+
+	// Constants sharing a name with different values collide
+	content = strings.Replace(content, "`json:\"name\"`\n\tPhone", "`json:\"company_name\"`\n\tPhone", 1)
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	_, err = build()
+	assert.ErrorContains(t, err, `constant JsonName of struct Company collides with the one of struct User`)
+}
+
+func TestModelBuilderBuildMaxIdentifierLength(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type User struct {
+	Name                   string ` + "`json:\"name\"`" + `
+	ShippingAddressLineOne string ` + "`json:\"shipping_address_line_one\"`" + `
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	config, err := NewConfig(&Config{
+		Input: ConfigInput{
+			Dir: tempDir,
+		},
+		Output: ConfigOutput{
+			MaxIdentifierLength: 20,
+		},
+		Elements: []ConfigTag{
+			{
+				Name: "json",
+				Input: ConfigTagInput{
+					Mode:        InputModeTypeTag,
+					TagPriority: []string{"json"},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		model, err := NewModelBuilder(config).Build()
+		require.NoError(t, err)
+
+		constants := model.Packages[tempDir].Structs[0].Constants
+		require.Len(t, constants, 2)
+		assert.Equal(t, "JsonUserName", constants[0].Name)
+		// Truncated the same way on every run
+		assert.Equal(t, "JsonUserShip92873fd7", constants[1].Name)
+		assert.Equal(t, "shipping_address_line_one", constants[1].Value)
+	}
+
+	// A truncated identifier colliding with another one is an error
+	truncated := truncateIdentifier("JsonUserShippingAddressLineOne", 20)
+	builder := NewModelBuilder(config)
+	builder.model.AddStruct(tempDir, "main", &StructModel{
+		Name: "User",
+		Constants: []*ConstantOutput{
+			{Name: "JsonUserShippingAddressLineOne", Value: "shipping_address_line_one"},
+			{Name: truncated, Value: "other"},
+		},
+	})
+	err = builder.applyMaxIdentifierLength(20)
+	assert.ErrorContains(t, err, "identifiers JsonUserShippingAddressLineOne and "+truncated+" in "+tempDir+" are both truncated to "+truncated)
+}
+
+func TestTruncateIdentifier(t *testing.T) {
+	assert.Equal(t, "JsonUserName", truncateIdentifier("JsonUserName", 16))
+
+	truncated := truncateIdentifier("JsonUserShippingAddressLineOne", 16)
+	assert.Len(t, truncated, 16)
+	assert.True(t, strings.HasPrefix(truncated, "JsonUser"))
+	assert.Equal(t, truncated, truncateIdentifier("JsonUserShippingAddressLineOne", 16))
+	assert.NotEqual(t, truncated, truncateIdentifier("JsonUserShippingAddressLineTwo", 16))
+
+	// Unexported identifiers stay unexported
+	assert.Equal(t, "json", truncateIdentifier("jsonUserShippingAddressLineOne", 16)[:4])
+}
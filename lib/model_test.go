@@ -1,9 +1,12 @@
 package constago
 
 import (
+	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
 )
 
 func TestAddStruct_ImportAliasCollisions(t *testing.T) {
@@ -138,23 +141,161 @@ func TestAddStruct_ImportAliasCollisions(t *testing.T) {
 			},
 		}
 		model.AddStruct("github.com/test/package1", "package1", struct3)
+		model.Finalize()
 
 		// Get the package
 		pkg := model.Packages["github.com/test/package1"]
 		assert.NotNil(t, pkg, "Expected package to exist")
 
-		// Check first import
-		import1, exists := pkg.Imports["github.com/example/strings"]
+		// Aliases are assigned by path, so the first import in path order keeps its name
+		import1, exists := pkg.Imports["github.com/another/strings"]
 		assert.True(t, exists, "Expected first import to exist")
-		assert.Equal(t, "strings", import1.Name, "Expected first import alias to be 'strings'")
+		assert.Equal(t, "strings", import1.Name, "Expected first import name to be 'strings'")
+		assert.Equal(t, "", import1.Alias, "Expected first import to have no alias")
 
 		// Check second import
-		import2, exists := pkg.Imports["github.com/other/strings"]
+		import2, exists := pkg.Imports["github.com/example/strings"]
 		assert.True(t, exists, "Expected second import to exist")
 		assert.Equal(t, "_strings", import2.Alias, "Expected second import alias to be '_strings'")
 
-		import3, exists := pkg.Imports["github.com/another/strings"]
+		import3, exists := pkg.Imports["github.com/other/strings"]
 		assert.True(t, exists, "Expected third import to exist")
-		assert.Equal(t, "__strings", import3.Alias, "Expected third import alias to be '_strings'")
+		assert.Equal(t, "__strings", import3.Alias, "Expected third import alias to be '__strings'")
 	})
 }
+
+func TestModelDump_SchemaVersion(t *testing.T) {
+	model := NewModel(nil)
+	model.AddStruct("github.com/test/package1", "package1", &StructModel{
+		Name:      "User",
+		Constants: []*ConstantOutput{{Name: "JsonUserName", Value: "name"}},
+	})
+
+	t.Run("json", func(t *testing.T) {
+		data, err := model.DumpJSON()
+		assert.NoError(t, err)
+
+		var dumped map[string]any
+		assert.NoError(t, json.Unmarshal(data, &dumped))
+		assert.Equal(t, float64(ModelSchemaVersion), dumped["schema_version"])
+	})
+
+	t.Run("yaml", func(t *testing.T) {
+		data, err := model.DumpYAML()
+		assert.NoError(t, err)
+
+		var dumped map[string]any
+		assert.NoError(t, yaml.Unmarshal(data, &dumped))
+		assert.Equal(t, ModelSchemaVersion, dumped["schema_version"])
+	})
+
+	t.Run("snake case keys", func(t *testing.T) {
+		data, err := model.DumpYAML()
+		assert.NoError(t, err)
+
+		var dumped struct {
+			Packages map[string]struct {
+				Structs []struct {
+					LineNumber int `yaml:"line_number"`
+					Constants  []struct {
+						Name  string `yaml:"name"`
+						Value string `yaml:"value"`
+					} `yaml:"constants"`
+				} `yaml:"structs"`
+			} `yaml:"packages"`
+		}
+		assert.NoError(t, yaml.Unmarshal(data, &dumped))
+		assert.Equal(t, "JsonUserName", dumped.Packages["github.com/test/package1"].Structs[0].Constants[0].Name)
+		assert.NotContains(t, string(data), "LineNumber")
+	})
+}
+
+func TestModelFinalize_DeterministicAliases(t *testing.T) {
+	paths := []string{
+		"github.com/other/strings",
+		"github.com/example/strings",
+		"strings",
+		"github.com/example/uuid",
+	}
+	orders := [][]int{{0, 1, 2, 3}, {3, 2, 1, 0}, {2, 0, 3, 1}}
+
+	var expected map[string]string
+	for _, order := range orders {
+		model := NewModel(nil)
+		for _, i := range order {
+			path := paths[i]
+			name := path[strings.LastIndex(path, "/")+1:]
+			model.AddStruct("github.com/test/package1", "package1", &StructModel{
+				Name: "User",
+				Getters: []*GetterOutput{
+					{
+						Name: "GetName",
+						Returns: []*ReturnOutput{
+							{Value: &ValueOutput{TypePackage: &TypePackageOutput{Path: path, Name: name}}},
+						},
+					},
+				},
+			})
+		}
+		model.Finalize()
+
+		aliases := map[string]string{}
+		for path, imp := range model.Packages["github.com/test/package1"].Imports {
+			aliases[path] = imp.Alias
+		}
+		if expected == nil {
+			expected = aliases
+			continue
+		}
+		assert.Equal(t, expected, aliases, "order %v", order)
+	}
+
+	assert.Equal(t, map[string]string{
+		"github.com/example/strings": "",
+		"github.com/example/uuid":    "",
+		"github.com/other/strings":   "_strings",
+		"strings":                    "__strings",
+	}, expected)
+}
+
+func TestModelFinalize_KeepsSourceAliases(t *testing.T) {
+	model := NewModel(nil)
+	addValue := func(structName string, imp *TypePackageOutput) {
+		model.AddStruct("github.com/test/package1", "package1", &StructModel{
+			Name: structName,
+			Getters: []*GetterOutput{
+				{
+					Name: "GetValue",
+					Returns: []*ReturnOutput{
+						{Value: &ValueOutput{TypeName: imp.Alias + ".Boolean", TypePackage: imp}},
+					},
+				},
+			},
+		})
+	}
+	// The source imports binary "github.com/example/booleans"
+	addValue("User", &TypePackageOutput{Path: "github.com/example/booleans", Name: "booleans", Alias: "binary"})
+	addValue("Admin", &TypePackageOutput{Path: "github.com/other/booleans", Name: "booleans"})
+	model.Finalize()
+	model.Finalize()
+
+	imports := model.Packages["github.com/test/package1"].Imports
+	assert.Equal(t, "binary", imports["github.com/example/booleans"].Alias)
+	// The alias frees the package name for the other import
+	assert.Equal(t, "", imports["github.com/other/booleans"].Alias)
+}
+
+func TestAddStruct_AppendsStructsOfSamePackage(t *testing.T) {
+	model := NewModel(nil)
+
+	model.AddStruct("github.com/test/package1", "package1", &StructModel{Name: "User", File: "user.go"})
+	model.AddStruct("github.com/test/package1", "package1", &StructModel{Name: "Company", File: "company.go"})
+
+	pkg := model.Packages["github.com/test/package1"]
+	if assert.NotNil(t, pkg) && assert.Len(t, pkg.Structs, 2) {
+		assert.Equal(t, "User", pkg.Structs[0].Name)
+		assert.Equal(t, "Company", pkg.Structs[1].Name)
+	}
+	assert.Equal(t, 1, model.PackagesFound)
+	assert.Equal(t, 2, model.StructsFound)
+}
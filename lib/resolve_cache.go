@@ -0,0 +1,114 @@
+package constago
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// packageNameResolver resolves the package name of an import path from the
+// module at moduleDir. It's a variable so it can be replaced in tests
+var packageNameResolver = getPackageNameFromGoList
+
+// packageNameCache keeps the package names resolved by packageNameResolver
+// in files under dir, one per module, named after the hash of the module
+// directory and its go.sum, so any change of the dependencies invalidates it
+type packageNameCache struct {
+	dir   string
+	files map[string]*packageNameCacheFile
+}
+
+type packageNameCacheFile struct {
+	path  string
+	names map[string]string
+	dirty bool
+}
+
+func newPackageNameCache(dir string) *packageNameCache {
+	return &packageNameCache{dir: dir, files: map[string]*packageNameCacheFile{}}
+}
+
+// file returns the cache file of the module at moduleDir, loading it on first use
+func (c *packageNameCache) file(moduleDir string) (*packageNameCacheFile, error) {
+	if f, ok := c.files[moduleDir]; ok {
+		return f, nil
+	}
+
+	sum, err := os.ReadFile(filepath.Join(moduleDir, "go.sum"))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read go.sum of the resolve cache: %w", err)
+	}
+	absDir, err := filepath.Abs(moduleDir)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.New()
+	hash.Write([]byte(absDir + "\n"))
+	hash.Write(sum)
+
+	f := &packageNameCacheFile{
+		path:  filepath.Join(c.dir, hex.EncodeToString(hash.Sum(nil))[:32]+".json"),
+		names: map[string]string{},
+	}
+	if data, err := os.ReadFile(f.path); err == nil {
+		// A corrupt cache is just rebuilt
+		if json.Unmarshal(data, &f.names) != nil {
+			f.names = map[string]string{}
+		}
+	}
+	c.files[moduleDir] = f
+	return f, nil
+}
+
+// save writes the cache files that got new package names
+func (c *packageNameCache) save() error {
+	for _, f := range c.files {
+		if !f.dirty {
+			continue
+		}
+		if err := os.MkdirAll(c.dir, 0755); err != nil {
+			return fmt.Errorf("failed to create the resolve cache directory: %w", err)
+		}
+		data, err := json.MarshalIndent(f.names, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(f.path, data, 0644); err != nil {
+			return fmt.Errorf("failed to write the resolve cache: %w", err)
+		}
+		f.dirty = false
+	}
+	return nil
+}
+
+// resolvePackageName resolves the package name of an external import path,
+// reading and filling the resolve cache when input.resolve_cache is set
+func (b *modelBuilder) resolvePackageName(importPath string, moduleDir string) (string, error) {
+	if b.resolveCache == nil {
+		return packageNameResolver(importPath, moduleDir)
+	}
+
+	f, err := b.resolveCache.file(moduleDir)
+	if err != nil {
+		return "", err
+	}
+	if name, ok := f.names[importPath]; ok {
+		return name, nil
+	}
+
+	name, err := packageNameResolver(importPath, moduleDir)
+	if err != nil {
+		return "", err
+	}
+	// Unresolved paths aren't cached, since they may be resolved later
+	if name != "" {
+		f.names[importPath] = name
+		f.dirty = true
+	}
+	return name, nil
+}
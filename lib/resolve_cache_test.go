@@ -0,0 +1,78 @@
+package constago
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModelBuilderBuildResolveCache(t *testing.T) {
+	tempDir := t.TempDir()
+	cacheDir := filepath.Join(t.TempDir(), "cache")
+
+	goMod := "module example.com/app\n\ngo 1.22\n"
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "go.mod"), []byte(goMod), 0644))
+	goSum := "github.com/acme/ids v1.0.0 h1:abc=\n"
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "go.sum"), []byte(goSum), 0644))
+
+	content := `package main
+
+import "github.com/acme/ids"
+
+type User struct {
+	ID ids.ID
+}
+`
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "user.go"), []byte(content), 0644))
+
+	calls := 0
+	original := packageNameResolver
+	packageNameResolver = func(importPath string, moduleDir string) (string, error) {
+		calls++
+		return "ids", nil
+	}
+	t.Cleanup(func() { packageNameResolver = original })
+
+	build := func() *Model {
+		config, err := NewConfig(&Config{
+			Input: ConfigInput{
+				Dir:          tempDir,
+				ResolveCache: cacheDir,
+			},
+			Getters: []ConfigGetter{
+				{
+					Name:    "Get",
+					Returns: []string{":value"},
+				},
+			},
+		})
+		require.NoError(t, err)
+
+		model, err := NewModelBuilder(config).Build()
+		require.NoError(t, err)
+		return model
+	}
+
+	model := build()
+	assert.Equal(t, 1, calls)
+	require.Contains(t, model.Packages[tempDir].Imports, "github.com/acme/ids")
+	assert.Equal(t, "ids", model.Packages[tempDir].Imports["github.com/acme/ids"].Name)
+
+	entries, err := os.ReadDir(cacheDir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	// The second run reads the cache instead of resolving again
+	model = build()
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, "ids", model.Packages[tempDir].Imports["github.com/acme/ids"].Name)
+
+	// Changing go.sum invalidates the cache
+	goSum += "github.com/acme/ids v1.0.0/go.mod h1:def=\n"
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "go.sum"), []byte(goSum), 0644))
+	build()
+	assert.Equal(t, 2, calls)
+}
@@ -0,0 +1,47 @@
+package constago
+
+import (
+	"strings"
+	"sync"
+)
+
+// customTransformPrefix marks a value_case naming a transform registered with
+// RegisterTransform, e.g. "custom:kebab"
+const customTransformPrefix = "custom:"
+
+var (
+	transformsMu sync.RWMutex
+	transforms   = map[string]func(string) string{}
+)
+
+// RegisterTransform registers a value transform by name, so elements can
+// apply it with value_case "custom:<name>". Registering a name again
+// replaces its transform.
+func RegisterTransform(name string, fn func(string) string) {
+	transformsMu.Lock()
+	defer transformsMu.Unlock()
+	transforms[name] = fn
+}
+
+// customTransform returns the registered transform of a custom value case
+func customTransform(caseType TransformCaseType) (func(string) string, bool) {
+	name, ok := strings.CutPrefix(string(caseType), customTransformPrefix)
+	if !ok {
+		return nil, false
+	}
+	transformsMu.RLock()
+	defer transformsMu.RUnlock()
+	fn, ok := transforms[name]
+	return fn, ok && fn != nil
+}
+
+// isCustomTransform reports whether the value case names a custom transform
+func isCustomTransform[T ~string](caseType T) bool {
+	return strings.HasPrefix(string(caseType), customTransformPrefix)
+}
+
+// isRegisteredTransform reports whether the custom value case was registered
+func isRegisteredTransform[T ~string](caseType T) bool {
+	_, ok := customTransform(TransformCaseType(caseType))
+	return ok
+}
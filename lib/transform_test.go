@@ -0,0 +1,68 @@
+package constago
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterTransform(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFile := filepath.Join(tempDir, "user.go")
+	content := `package main
+
+type User struct {
+	FirstName string
+}
+`
+	require.NoError(t, os.WriteFile(testFile, []byte(content), 0644))
+
+	buildConfig := func(valueCase TransformCaseType) (*Config, error) {
+		return NewConfig(&Config{
+			Input: ConfigInput{
+				Dir: tempDir,
+			},
+			Elements: []ConfigTag{
+				{
+					Name: "field",
+					Input: ConfigTagInput{
+						Mode: InputModeTypeField,
+					},
+					Output: ConfigTagOutput{
+						Transform: ConfigTagOutputTransform{
+							ValueCase:      valueCase,
+							ValueSeparator: "-",
+						},
+					},
+				},
+			},
+		})
+	}
+
+	_, err := buildConfig("custom:shout")
+	assert.ErrorContains(t, err, `is not a registered transform, see RegisterTransform`)
+
+	RegisterTransform("shout", func(s string) string {
+		return strings.ToUpper(s) + "!"
+	})
+	t.Cleanup(func() {
+		transformsMu.Lock()
+		delete(transforms, "shout")
+		transformsMu.Unlock()
+	})
+
+	config, err := buildConfig("custom:shout")
+	require.NoError(t, err)
+
+	builder := NewModelBuilder(config)
+	require.NoError(t, builder.scanFile(testFile))
+
+	constants := builder.model.Packages[tempDir].Structs[0].Constants
+	require.Len(t, constants, 1)
+	assert.Equal(t, "FIRST-NAME!", constants[0].Value)
+}
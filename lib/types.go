@@ -3,6 +3,17 @@ package constago
 const validSourceErrorMessage = "{{title}} must be a valid source pattern"
 const validIncludeErrorMessage = "{{title}} must have at least one element"
 const validGoIdentifierErrorMessage = "\"{{value}}\" is not a valid Go identifier"
+const validImportErrorMessage = "\"{{value}}\" is not a valid import, must be \"path\" or \"alias path\""
+const validResultNamesErrorMessage = "{{title}} must have one name per return"
+
+const validReservedResultNameErrorMessage = "\"{{value}}\" is reserved for the receiver or the error result of the getter"
+
+const validErrorExprErrorMessage = "{{title}} requires error_return"
+const validReceiverTypeErrorMessage = "{{title}} requires structs_matching, since the methods of every matching struct are declared on the same type"
+const validTemplateErrorMessage = "{{title}} must be a valid template"
+const validPackageNameWithGettersErrorMessage = "{{title}} can't be set when getters are configured, since methods must be declared in the package of the struct"
+
+const validPackageNameInSourceDirErrorMessage = "{{title}} can only name an external test package, ending with _test and written to a _test.go file_name, unless path_template writes the files elsewhere, since a folder holds a single package"
 
 // InputModeType
 type InputModeType string
@@ -11,12 +22,14 @@ const (
 	InputModeTypeTagThenField InputModeType = "tagThenField"
 	InputModeTypeField        InputModeType = "field"
 	InputModeTypeTag          InputModeType = "tag"
+	InputModeTypeGoType       InputModeType = "type"
 )
 
 var validNameOrTitleModes = []InputModeType{
 	InputModeTypeTagThenField,
 	InputModeTypeField,
 	InputModeTypeTag,
+	InputModeTypeGoType,
 }
 
 // OutputModeType
@@ -26,17 +39,21 @@ const (
 	OutputModeNone     OutputModeType = "none"
 	OutputModeStruct   OutputModeType = "struct"
 	OutputModeConstant OutputModeType = "constant"
+	// OutputModeDoc only records the values in the model, for dumps and the
+	// markdown format, while none values can still be returned by getters
+	OutputModeDoc OutputModeType = "doc"
 )
 
 var validOutputModes = []OutputModeType{
 	OutputModeNone,
 	OutputModeStruct,
 	OutputModeConstant,
+	OutputModeDoc,
 }
 
-const validOutputModesErrorMessage = "\"{{value}}\" is not a valid {{title}}, must be none, struct, constant"
+const validOutputModesErrorMessage = "\"{{value}}\" is not a valid {{title}}, must be none, struct, constant, doc"
 
-const validNameOrTitleModesErrorMessage = "\"{{value}}\" is not a valid {{title}}, must be tag, field, or tagThenField"
+const validNameOrTitleModesErrorMessage = "\"{{value}}\" is not a valid {{title}}, must be tag, field, tagThenField, or type"
 
 const validRegexErrorMessage = "{{title}} must be a valid regular expression"
 
@@ -59,6 +76,20 @@ var validConstantFormats = []ConstantFormatType{
 
 const validConstantFormatsErrorMessage = "\"{{value}}\" is not a valid {{title}}, must be camel, pascal, snake, snakeUpper"
 
+// IdentifierCaseNone keeps the generated identifiers as formatted by their
+// elements and getters, see output.identifier_case
+const IdentifierCaseNone ConstantFormatType = "none"
+
+var validIdentifierCases = append([]ConstantFormatType{IdentifierCaseNone}, validConstantFormats...)
+
+const validIdentifierCasesErrorMessage = "\"{{value}}\" is not a valid {{title}}, must be none, camel, pascal, snake, snakeUpper"
+
+// minMaxIdentifierLength is the lowest output.max_identifier_length, leaving
+// room for the hash of the truncated identifiers
+const minMaxIdentifierLength = 16
+
+const validMaxIdentifierLengthErrorMessage = "{{title}} must be 0 or at least 16"
+
 // TransformCaseType
 type TransformCaseType string
 
@@ -79,3 +110,121 @@ var validTransformCases = []TransformCaseType{
 }
 
 const validTransformCasesErrorMessage = "\"{{value}}\" is not a valid {{title}}, must be asIs, camel, pascal, upper, lower, title, sentence"
+
+const validCustomTransformErrorMessage = "\"{{value}}\" is not a registered transform, see RegisterTransform"
+
+// Normalizations are the cases accepted by output.normalization
+var validNormalizations = []TransformCaseType{
+	TransformCaseUpper,
+	TransformCaseLower,
+}
+
+const validNormalizationsErrorMessage = "\"{{value}}\" is not a valid {{title}}, must be upper, lower"
+
+// FieldOrderType
+type FieldOrderType string
+
+const (
+	FieldOrderSource       FieldOrderType = "source"
+	FieldOrderAlphabetical FieldOrderType = "alphabetical"
+	FieldOrderTag          FieldOrderType = "tag"
+)
+
+var validFieldOrders = []FieldOrderType{
+	FieldOrderSource,
+	FieldOrderAlphabetical,
+	FieldOrderTag,
+}
+
+const validFieldOrdersErrorMessage = "\"{{value}}\" is not a valid {{title}}, must be source, alphabetical, tag"
+
+// ConstBlockType
+type ConstBlockType string
+
+const (
+	ConstBlockGroup  ConstBlockType = "group"
+	ConstBlockSingle ConstBlockType = "single"
+)
+
+var validConstBlocks = []ConstBlockType{
+	ConstBlockGroup,
+	ConstBlockSingle,
+}
+
+const validConstBlocksErrorMessage = "\"{{value}}\" is not a valid {{title}}, must be group, single"
+
+// DeclarationType
+type DeclarationType string
+
+const (
+	DeclarationConst DeclarationType = "const"
+	DeclarationVar   DeclarationType = "var"
+)
+
+var validDeclarations = []DeclarationType{
+	DeclarationConst,
+	DeclarationVar,
+}
+
+const validDeclarationsErrorMessage = "\"{{value}}\" is not a valid {{title}}, must be const, var"
+
+// StructFieldTagsType
+type StructFieldTagsType string
+
+const (
+	StructFieldTagsNone StructFieldTagsType = "none"
+	StructFieldTagsCopy StructFieldTagsType = "copy"
+)
+
+var validStructFieldTags = []StructFieldTagsType{
+	StructFieldTagsNone,
+	StructFieldTagsCopy,
+}
+
+const validStructFieldTagsErrorMessage = "\"{{value}}\" is not a valid {{title}}, must be none, copy"
+
+// TagSyntaxType
+type TagSyntaxType string
+
+const (
+	TagSyntaxStandard TagSyntaxType = "standard"
+	TagSyntaxKeyValue TagSyntaxType = "keyvalue"
+	TagSyntaxProtobuf TagSyntaxType = "protobuf"
+)
+
+var validTagSyntaxes = []TagSyntaxType{
+	TagSyntaxStandard,
+	TagSyntaxKeyValue,
+	TagSyntaxProtobuf,
+}
+
+const validTagSyntaxesErrorMessage = "\"{{value}}\" is not a valid {{title}}, must be standard, keyvalue, protobuf"
+
+const validEmitValuesErrorMessage = "{{title}} requires typed_key"
+
+const validExpandOneofErrorMessage = "{{title}} requires the constant output mode"
+
+const validNoneNameErrorMessage = "{{title}} can't hold */ or line breaks, since it labels the getter values in a comment"
+
+const validAggregateToErrorMessage = "{{title}} requires the constant output mode without typed_key"
+
+const validTemplateEntryErrorMessage = "{{title}} requires output.templates"
+
+// OutputFormatType
+type OutputFormatType string
+
+const (
+	OutputFormatGo       OutputFormatType = "go"
+	OutputFormatMarkdown OutputFormatType = "markdown"
+)
+
+var validOutputFormats = []OutputFormatType{
+	OutputFormatGo,
+	OutputFormatMarkdown,
+}
+
+const validOutputFormatsErrorMessage = "\"{{value}}\" is not a valid {{title}}, must be go, markdown"
+
+const validIndentErrorMessage = "\"{{value}}\" is not a valid {{title}}, must be tab or spaces:N"
+
+const validGoFormatOnlyErrorMessage = "{{title}} can't be combined with the markdown format"
@@ -151,6 +151,64 @@ func isValidSource(pattern string) bool {
 	return true
 }
 
+// unexportName lowers the leading upper case letters of an identifier so it's
+// unexported, keeping the start of the next word, e.g. APIUser becomes apiUser
+func unexportName(name string) string {
+	runes := []rune(name)
+	i := 0
+	for i < len(runes) && unicode.IsUpper(runes[i]) {
+		i++
+	}
+	if i > 1 && i < len(runes) && unicode.IsLower(runes[i]) {
+		i--
+	}
+	for j := 0; j < i; j++ {
+		runes[j] = unicode.ToLower(runes[j])
+	}
+	return string(runes)
+}
+
+// capitalizeFirst upper cases the first letter of a word, keeping the rest,
+// e.g. key becomes Key and iD becomes ID
+func capitalizeFirst(word string) string {
+	runes := []rune(word)
+	if len(runes) > 0 {
+		runes[0] = unicode.ToUpper(runes[0])
+	}
+	return string(runes)
+}
+
 func isStringBlank[T ~string](s T) bool {
 	return len(strings.TrimSpace(string(s))) == 0
 }
+
+// parseImport parses an import entry written as "path" or "alias path"
+func parseImport(entry string) *TypePackageOutput {
+	parts := strings.Fields(entry)
+	if len(parts) == 0 {
+		return &TypePackageOutput{}
+	}
+
+	path := strings.Trim(parts[len(parts)-1], "\"")
+	name := path
+	if i := strings.LastIndex(path, "/"); i >= 0 {
+		name = path[i+1:]
+	}
+
+	if len(parts) == 2 {
+		return &TypePackageOutput{Path: path, Name: parts[0], Alias: parts[0]}
+	}
+	return &TypePackageOutput{Path: path, Name: name}
+}
+
+// isValidImport checks if an import entry is "path" or "alias path"
+func isValidImport(entry string) bool {
+	parts := strings.Fields(entry)
+	if len(parts) == 0 || len(parts) > 2 {
+		return false
+	}
+	if len(parts) == 2 && parts[0] != "_" && parts[0] != "." && !isValidGoIdentifier(parts[0]) {
+		return false
+	}
+	return strings.Trim(parts[len(parts)-1], "\"") != ""
+}
@@ -1,8 +1,16 @@
 package constago
 
-import "regexp"
+import (
+	"regexp"
+	"text/template"
+)
 
 func isValidRegex(s string) bool {
 	_, err := regexp.Compile(s)
 	return err == nil
 }
+
+func isValidTemplate(s string) bool {
+	_, err := template.New("").Parse(s)
+	return err == nil
+}